@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"pit/internal/api"
+	"pit/internal/core"
+	"pit/pkg/pitapi"
+)
+
+// set in the environment of the re-executed engine
+const daemonEnv = "PIT_DAEMON"
+
+const daemonStartTimeout = 20 * time.Second
+
+func isDaemonChild() bool {
+	return os.Getenv(daemonEnv) == "1"
+}
+
+////////////////////////////////////////////////////////
+// DETACH (pit start -d)
+////////////////////////////////////////////////////////
+
+// startDaemon re-executes pit in a new session with its output going to
+// runtime/logs/pit.log, and waits until the control socket answers.
+func startDaemon(engine *core.Engine) int {
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Println("Cannot locate pit binary:", err)
+		return 1
+	}
+
+	logPath := engine.LogFile()
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		fmt.Println("Cannot create log dir:", err)
+		return 1
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		fmt.Println("Cannot open log file:", err)
+		return 1
+	}
+	defer logFile.Close()
+
+	var args []string
+	for _, a := range os.Args[1:] {
+		if a != "-d" && a != "--detach" {
+			args = append(args, a)
+		}
+	}
+
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := cmd.Start(); err != nil {
+		fmt.Println("Cannot start daemon:", err)
+		return 1
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(daemonStartTimeout)
+	tick := time.NewTicker(200 * time.Millisecond)
+	defer tick.Stop()
+
+	for {
+		select {
+		case err := <-exited:
+			fmt.Println("pit daemon exited during startup:", err)
+			fmt.Println("Last log lines:")
+			_ = tailLines(os.Stdout, logPath, 15)
+			return 1
+
+		case <-deadline:
+			fmt.Println("pit daemon did not become ready within", daemonStartTimeout)
+			fmt.Println("See:", logPath)
+			return 1
+
+		case <-tick.C:
+			if daemonRunning(engine.BasePath) {
+				fmt.Printf("✔ pit started in background (pid %d)\n", cmd.Process.Pid)
+				fmt.Println("  Logs:", logPath)
+				return 0
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////
+// CONTROL SOCKET
+////////////////////////////////////////////////////////
+
+// daemonRunning reports whether an engine answers on the control socket.
+func daemonRunning(base string) bool {
+	conn, err := net.DialTimeout("unix", api.ControlSocketPath(base), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+////////////////////////////////////////////////////////
+// pit status
+////////////////////////////////////////////////////////
+
+// handleStatusCommand runs when no daemon answers on the control
+// socket (see remoteStatus for the running case).
+func handleStatusCommand(engine *core.Engine) {
+	if pid, ok := engine.Running(); ok {
+		fmt.Printf("pit is running (pid %d) but its control socket does not answer\n", pid)
+		os.Exit(1)
+	}
+	fmt.Println("pit is not running")
+	os.Exit(3)
+}
+
+func printEngineStatus(st *pitapi.EngineStatus) {
+	fmt.Printf("pit is running (pid %d, up %s, PHP %s)\n\n",
+		st.PID, formatUptime(st.Uptime), st.PHPVersion)
+
+	printStatusTable("Services", st.Services)
+
+	projects := make([]string, 0, len(st.Projects))
+	for name := range st.Projects {
+		projects = append(projects, name)
+	}
+	sort.Strings(projects)
+
+	for _, name := range projects {
+		fmt.Println()
+		printStatusTable("Project "+name, st.Projects[name])
+	}
+}
+
+func printStatusTable(title string, statuses map[string]pitapi.ServiceStatus) {
+	fmt.Println(title + ":")
+
+	names := make([]string, 0, len(statuses))
+	for n := range statuses {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("  %-28s %-8s %-7s %-6s %-9s %s\n", "SERVICE", "STATE", "PID", "PORT", "UPTIME", "HEALTH")
+	for _, n := range names {
+		st := statuses[n]
+
+		state := "stopped"
+		if st.Running {
+			state = "running"
+		}
+		pid, port, uptime := "-", "-", "-"
+		if st.PID > 0 {
+			pid = strconv.Itoa(st.PID)
+		}
+		if st.Port > 0 {
+			port = strconv.Itoa(st.Port)
+		}
+		if st.Running {
+			uptime = formatUptime(st.Uptime)
+		}
+
+		health := st.Health
+		if st.Restarts > 0 {
+			health += fmt.Sprintf(" (%d restarts, last: %s)", st.Restarts, st.LastExit)
+		}
+
+		fmt.Printf("  %-28s %-8s %-7s %-6s %-9s %s\n", n, state, pid, port, uptime, health)
+	}
+}
+
+func formatUptime(sec int64) string {
+	d := time.Duration(sec) * time.Second
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return d.String()
+	}
+}
+
+////////////////////////////////////////////////////////
+// pit logs
+////////////////////////////////////////////////////////
+
+func handleLogsCommand(engine *core.Engine) {
+	follow := false
+	lines := 100
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-f", "--follow":
+			follow = true
+		case "-n":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					lines = n
+				}
+				i++
+			}
+		}
+	}
+
+	logPath := engine.LogFile()
+	if err := tailLines(os.Stdout, logPath, lines); err != nil {
+		fmt.Println("No log file:", logPath)
+		os.Exit(1)
+	}
+
+	if follow {
+		followFile(os.Stdout, logPath)
+	}
+}
+
+// tailLines prints the last n lines of path.
+func tailLines(w io.Writer, path string, n int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var ring []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		ring = append(ring, sc.Text())
+		if len(ring) > n {
+			ring = ring[1:]
+		}
+	}
+
+	fmt.Fprint(w, strings.Join(ring, "\n"))
+	if len(ring) > 0 {
+		fmt.Fprintln(w)
+	}
+	return sc.Err()
+}
+
+// followFile streams data appended to path until interrupted.
+func followFile(w io.Writer, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	offset, _ := f.Seek(0, io.SeekEnd)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	tick := time.NewTicker(300 * time.Millisecond)
+	defer tick.Stop()
+
+	buf := make([]byte, 32*1024)
+	for {
+		select {
+		case <-sigs:
+			return
+		case <-tick.C:
+		}
+
+		// log rotated / truncated: start over
+		if st, err := os.Stat(path); err == nil && st.Size() < offset {
+			offset, _ = f.Seek(0, io.SeekStart)
+		}
+
+		for {
+			n, err := f.Read(buf)
+			if n > 0 {
+				_, _ = w.Write(buf[:n])
+				offset += int64(n)
+			}
+			if err != nil || n == 0 {
+				break
+			}
+		}
+	}
+}
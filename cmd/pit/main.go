@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"pit/internal/api"
+	"pit/internal/certs"
 	"pit/internal/core"
-	"pit/internal/tools"
+	"pit/internal/templates"
+	"pit/pkg/pitapi"
 )
 
 func main() {
@@ -18,6 +26,13 @@ func main() {
 	}
 
 	base, _ := filepath.Abs(filepath.Dir(os.Args[0]))
+
+	// a running daemon owns the stack: talk to it instead of building
+	// a second engine in this process
+	if daemonRunning(base) && runRemote(base) {
+		return
+	}
+
 	engine := core.NewEngine(base)
 
 	switch os.Args[1] {
@@ -37,6 +52,17 @@ func main() {
 	// START ENGINE
 	// ----------------------------
 	case "start":
+		if pid, ok := engine.Running(); ok {
+			fmt.Printf("pit already running (pid %d)\n", pid)
+			os.Exit(1)
+		}
+
+		if hasFlag(os.Args[2:], "-d") || hasFlag(os.Args[2:], "--detach") {
+			if !isDaemonChild() {
+				os.Exit(startDaemon(engine))
+			}
+		}
+
 		results := engine.PreflightChecks()
 		if !printChecks(results) {
 			fmt.Println("\nCannot start: preflight checks failed.")
@@ -48,17 +74,16 @@ func main() {
 
 		fmt.Printf("Starting services.\n")
 		if err := engine.StartAll(); err != nil {
-			fmt.Println("Error starting services:", err)
+			if checks, ok := core.ConfigChecks(err); ok {
+				fmt.Println("Error starting services: nginx rejected the generated config")
+				printChecks(checks)
+			} else {
+				fmt.Println("Error starting services:", err)
+			}
 			os.Exit(1)
 		}
 
-		go func() {
-			fmt.Println("✔ API started on http://localhost:7070")
-			api.StartAPIServer(engine)
-		}()
-
-		fmt.Println("\nEngine is ready.")
-		select {}
+		os.Exit(serveUntilSignal(engine))
 
 	// ----------------------------
 	// STOP ENGINE
@@ -71,6 +96,20 @@ func main() {
 		}
 		os.Exit(0)
 
+	// ----------------------------
+	// DAEMON STATUS / LOGS
+	// ----------------------------
+	case "status":
+		handleStatusCommand(engine)
+
+	case "logs":
+		handleLogsCommand(engine)
+
+	case "events":
+		// only reached without a daemon (see runRemote)
+		fmt.Println("pit is not running")
+		os.Exit(3)
+
 	// ----------------------------
 	// API ONLY
 	// ----------------------------
@@ -83,11 +122,15 @@ func main() {
 	// ----------------------------
 	case "php":
 		handlePHPCommand(engine)
+	case "nginx":
+		handleNginxCommand(engine)
 
 	case "project":
 		handleProjectCommand(engine)
 	case "tools":
 		handleToolsCommand(engine)
+	case "templates":
+		handleTemplatesCommand(engine)
 
 	default:
 		fmt.Println("Unknown command:", os.Args[1])
@@ -95,6 +138,76 @@ func main() {
 	}
 }
 
+// exit codes of the foreground engine
+const (
+	exitOK       = 0
+	exitStopFail = 1
+	exitAPIFail  = 2
+)
+
+// serveUntilSignal runs the API (control socket + optional TCP) next
+// to the started engine and blocks until SIGINT/SIGTERM (or an API
+// /stop), then tears the whole stack down. SIGHUP reloads
+// config/engine.json instead of exiting.
+func serveUntilSignal(engine *core.Engine) int {
+	apiErr := make(chan error, 2)
+
+	srv, err := api.NewServer(engine)
+	if err != nil {
+		apiErr <- err
+		srv = &api.Server{}
+	} else {
+		srv.Serve(apiErr)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	fmt.Println("\nEngine is ready.")
+
+	code := exitOK
+	for {
+		select {
+		case sig := <-sigs:
+			if sig == syscall.SIGHUP {
+				if err := engine.ReloadConfig(); err != nil {
+					fmt.Println("Reload failed:", err)
+				}
+				continue
+			}
+			fmt.Printf("\nReceived %s, shutting down...\n", sig)
+
+		case err := <-apiErr:
+			fmt.Println("API server failed:", err)
+			code = exitAPIFail
+
+		case <-engine.Done():
+			// stopped through the API: services are already down
+			shutdownAPI(srv)
+			return exitOK
+		}
+		break
+	}
+
+	shutdownAPI(srv)
+
+	if err := engine.StopAll(); err != nil {
+		fmt.Println("Error stopping engine:", err)
+		return exitStopFail
+	}
+	return code
+}
+
+func shutdownAPI(srv *api.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		fmt.Println("API shutdown:", err)
+	}
+}
+
 func printChecks(results []core.CheckResult) bool {
 	ok := true
 	for _, r := range results {
@@ -152,12 +265,130 @@ func handlePHPCommand(engine *core.Engine) {
 	case "current":
 		fmt.Println("Current PHP version:", engine.CurrentPHPVersion())
 
+	case "install":
+		src, opts, err := installArgs(os.Args[3:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			fmt.Println("Usage: pit php install <archive.tar.gz|dir> [--as=<version>] [--no-verify]")
+			return
+		}
+		ver, err := engine.InstallPHP(src, opts)
+		if err != nil {
+			fmt.Println("Install failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✔ PHP installed:", filepath.Join(engine.BasePath, "php", ver))
+		fmt.Println("  Use it with: pit php use", ver, "or php_version in a project config")
+
+	case "remove":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: pit php remove <version>")
+			return
+		}
+		if err := engine.RemovePHP(os.Args[3]); err != nil {
+			fmt.Println("Remove failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✔ PHP removed:", os.Args[3])
+
+	case "ext":
+		action, ext, req, err := extArgs(os.Args[3:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			printPHPUsage()
+			return
+		}
+		version := req.Version
+		if version == "" {
+			version = engine.CurrentPHPVersion()
+		}
+
+		if action == "list" {
+			list, err := core.ListPHPExtensions(engine.BasePath, version)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			printPHPExtensions(version, list)
+			return
+		}
+
+		on := action == "enable"
+		if req.Project != "" {
+			reg := core.NewProjectRegistry(engine.BasePath)
+			cfg, err := reg.SetProjectExtension(req.Project, ext, on)
+			if cfg == nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			printExtensionChange(ext, on, "project "+cfg.Name)
+			if err != nil {
+				fmt.Println("Warning:", err)
+			}
+			return
+		}
+
+		set := core.DisablePHPExtension
+		if on {
+			set = core.EnablePHPExtension
+		}
+		if err := set(engine.BasePath, version, ext); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		printExtensionChange(ext, on, "PHP "+version)
+
 	default:
 		fmt.Println("Unknown php command:", os.Args[2])
 		printPHPUsage()
 	}
 }
 
+////////////////////////////////////////////////////////
+// NGINX SUBCOMMANDS
+////////////////////////////////////////////////////////
+
+func handleNginxCommand(engine *core.Engine) {
+	if len(os.Args) < 3 {
+		printNginxUsage()
+		return
+	}
+
+	switch os.Args[2] {
+
+	case "install":
+		src, opts, err := installArgs(os.Args[3:])
+		if err != nil || opts.Name != "" {
+			fmt.Println("Usage: pit nginx install <archive.tar.gz|dir> [--no-verify]")
+			return
+		}
+		ver, err := engine.InstallNginx(src, opts)
+		if err != nil {
+			fmt.Println("Install failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✔ nginx", ver, "installed")
+		fmt.Println("  Run pit setup to let it bind ports 80/443")
+
+	case "remove":
+		if err := engine.RemoveNginx(); err != nil {
+			fmt.Println("Remove failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✔ nginx removed")
+
+	default:
+		fmt.Println("Unknown nginx command:", os.Args[2])
+		printNginxUsage()
+	}
+}
+
+func printNginxUsage() {
+	fmt.Println("Nginx Commands:")
+	fmt.Println("  pit nginx install <archive.tar.gz|dir> [--no-verify]")
+	fmt.Println("  pit nginx remove")
+}
+
 ////////////////////////////////////////////////////////
 // PROJECT SUBCOMMANDS (FINAL)
 ////////////////////////////////////////////////////////
@@ -172,6 +403,94 @@ func handleProjectCommand(engine *core.Engine) {
 
 	switch os.Args[2] {
 
+	case "create":
+		if len(os.Args) < 4 {
+			fmt.Println("Missing project name.")
+			return
+		}
+		name := os.Args[3]
+
+		if err := reg.Create(name); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		cfg, _ := reg.LoadConfig(name)
+		fmt.Println("✔ Project created:", name)
+		if cfg != nil {
+			printProjectCreated(engine.BasePath, name, cfg.Root, cfg.Port)
+		}
+
+	case "delete":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: pit project delete <name> [--archive]")
+			return
+		}
+		name := os.Args[3]
+		archive := hasFlag(os.Args[4:], "--archive")
+
+		dst, err := reg.Delete(name, archive)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		printProjectDeleted(name, dst)
+
+	case "start":
+		if len(os.Args) < 4 {
+			fmt.Println("Missing project name.")
+			return
+		}
+		name := os.Args[3]
+
+		peng, err := reg.Load(name)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if err := peng.Start(); err != nil {
+			fmt.Println("Start failed:", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Project started:", name)
+
+	case "stop":
+		if len(os.Args) < 4 {
+			fmt.Println("Missing project name.")
+			return
+		}
+		name := os.Args[3]
+
+		peng, err := reg.Load(name)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if err := peng.Stop(); err != nil {
+			fmt.Println("Stop failed:", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Project stopped:", name)
+
+	case "status":
+		if len(os.Args) < 4 {
+			fmt.Println("Missing project name.")
+			return
+		}
+		name := os.Args[3]
+
+		peng, err := reg.Load(name)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		printProjectStatus(name, peng.Status())
+
 	case "list":
 		projects, err := reg.List()
 		if err != nil {
@@ -195,53 +514,169 @@ func handleProjectCommand(engine *core.Engine) {
 			return
 		}
 
-		fmt.Println("Project:", cfg.Name)
-		fmt.Println("PHP Version:", cfg.PHPVersion)
-		fmt.Println("Port:", cfg.Port)
-		fmt.Println("Root:", cfg.Root)
+		printProjectInfo(engine.BasePath, cfg)
+		printProjectDomains(engine.BasePath, cfg.Hostnames(), cfg.Aliases)
 
-	case "set-port":
-		if len(os.Args) < 5 {
-			fmt.Println("Usage: pit project set-port <name> <port>")
+	case "domain":
+		if len(os.Args) < 6 {
+			fmt.Println("Usage: pit project domain <name> <add|remove> <host> [--alias]")
+			return
+		}
+		name := os.Args[3]
+
+		cfg, err := reg.LoadConfig(name)
+		if err != nil {
+			fmt.Println("Error:", err)
 			return
 		}
 
+		patch, err := domainPatch(cfg.Domains, cfg.Aliases, os.Args[4:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+
+		// validates + checks other projects / www / tools, then restarts;
+		// the running engine picks the change up and reloads nginx
+		cfg, err = reg.Update(name, patch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Project", name, "domains updated")
+		printProjectDomains(engine.BasePath, cfg.Hostnames(), cfg.Aliases)
+
+	case "framework":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: pit project framework <name> [<framework>|auto]")
+			return
+		}
 		name := os.Args[3]
-		portStr := os.Args[4]
 
-		port, err := strconv.Atoi(portStr)
+		// no value: show what is used and why
+		if len(os.Args) < 5 {
+			cfg, err := reg.LoadConfig(name)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Println("Framework:", frameworkLabel(engine.BasePath, cfg))
+			fmt.Println("Available:", strings.Join(templates.New(engine.BasePath).Frameworks(), ", "))
+			return
+		}
+
+		fw := os.Args[4]
+		cfg, err := reg.Update(name, core.ProjectConfigPatch{Framework: &fw})
 		if err != nil {
-			fmt.Println("Invalid port:", portStr)
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Project", name, "framework:", frameworkLabel(engine.BasePath, cfg))
+
+	case "set-root":
+		if len(os.Args) < 5 {
+			fmt.Println("Usage: pit project set-root <name> <dir>")
 			return
 		}
+		name, root := os.Args[3], os.Args[4]
 
-		cfg, err := reg.LoadConfig(name)
+		cfg, err := reg.Update(name, core.ProjectConfigPatch{Root: &root})
 		if err != nil {
-			fmt.Println("Error loading config:", err)
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Project", name, "document root:", cfg.Root)
+
+	case "proxy":
+		if len(os.Args) < 5 {
+			fmt.Println("Usage: pit project proxy <name> <upstream-port> <command...> | off")
 			return
 		}
+		name := os.Args[3]
 
-		cfg.Port = port
+		patch, err := proxyPatch(os.Args[4:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		cfg, err := reg.Update(name, patch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		printProxyChange(cfg)
 
-		if err := reg.SaveConfig(name, cfg); err != nil {
-			fmt.Println("Error saving config:", err)
+	case "debug":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: pit project debug <name> [on|off] [--mode=debug,profile,coverage] [--port=<n>]")
 			return
 		}
+		name := os.Args[3]
 
-		fmt.Println("Restarting project", name)
+		// no value: show the current state
+		if len(os.Args) < 5 {
+			cfg, err := reg.LoadConfig(name)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			printDebugInfo(engine.BasePath, cfg)
+			return
+		}
+
+		patch, err := debugPatch(os.Args[4:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		cfg, err := reg.Update(name, patch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		printDebugInfo(engine.BasePath, cfg)
+
+	case "cron":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: pit project cron <name>")
+			return
+		}
+		name := os.Args[3]
 
 		peng, err := reg.Load(name)
 		if err != nil {
 			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if !printCronStatus(peng.Status()) {
+			fmt.Println("No cron jobs configured for", name)
 			return
 		}
+		fmt.Println("Log:", filepath.Join(engine.BasePath, "runtime", name, "logs", "cron.log"))
 
-		_ = peng.Stop()
-		if err := peng.Start(); err != nil {
-			fmt.Println("Failed to restart:", err)
+	case "set-port":
+		if len(os.Args) < 5 {
+			fmt.Println("Usage: pit project set-port <name> <port>")
 			return
 		}
 
+		name := os.Args[3]
+		portStr := os.Args[4]
+
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			fmt.Println("Invalid port:", portStr)
+			return
+		}
+
+		// validates the port, checks ports.json + live listeners,
+		// saves and restarts
+		if _, err := reg.Update(name, core.ProjectConfigPatch{Port: &port}); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
 		fmt.Println("Project", name, "updated to port", port)
 
 	case "restart":
@@ -286,16 +721,7 @@ func handleToolsCommand(engine *core.Engine) {
 	case "sync":
 		fmt.Println("[Tools] Scanning tools...")
 
-		mgr := tools.Manager{
-			Base:       engine.BasePath,
-			PhpSockAbs: engine.ToolsPHPSocket(),
-
-			NginxReload: func() error {
-				return engine.ReloadNginx()
-			},
-		}
-
-		if err := mgr.SyncAll(); err != nil {
+		if err := engine.SyncTools(); err != nil {
 			fmt.Println("Tools sync failed:", err)
 			return
 		}
@@ -312,24 +738,472 @@ func printToolsUsage() {
 	fmt.Println("  pit tools sync")
 }
 
+////////////////////////////////////////////////////////
+// TEMPLATES HANDLER
+////////////////////////////////////////////////////////
+
+func handleTemplatesCommand(engine *core.Engine) {
+	set := templates.New(engine.BasePath)
+
+	if len(os.Args) < 3 {
+		printTemplatesUsage()
+		return
+	}
+
+	switch os.Args[2] {
+
+	case "list":
+		for _, name := range set.Names() {
+			if set.Overridden(name) {
+				fmt.Printf("- %-28s (overridden in %s)\n", name, set.OverrideDir())
+			} else {
+				fmt.Println("-", name)
+			}
+		}
+
+	case "export":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: pit templates export <name>")
+			return
+		}
+		dst, err := set.Export(os.Args[3])
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✔ Template exported:", dst)
+		fmt.Println("  Edit it, then restart pit (or the project) to apply")
+
+	default:
+		fmt.Println("Unknown templates command:", os.Args[2])
+		printTemplatesUsage()
+	}
+}
+
+func printTemplatesUsage() {
+	fmt.Println("Templates Commands:")
+	fmt.Println("  pit templates list")
+	fmt.Println("  pit templates export <name>")
+}
+
 ////////////////////////////////////////////////////////
 // HELPERS
 ////////////////////////////////////////////////////////
 
+func hasFlag(args []string, flag string) bool {
+	for _, a := range args {
+		if a == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// installArgs parses "<src> [--as=<name>] [--no-verify]".
+func installArgs(args []string) (string, core.InstallOptions, error) {
+	var opts core.InstallOptions
+	var src string
+	for _, a := range args {
+		switch {
+		case a == "--no-verify":
+			opts.NoVerify = true
+		case strings.HasPrefix(a, "--as="):
+			opts.Name = strings.TrimPrefix(a, "--as=")
+		case strings.HasPrefix(a, "--"):
+			return "", opts, fmt.Errorf("unknown flag %s", a)
+		case src == "":
+			src = a
+		default:
+			return "", opts, fmt.Errorf("unexpected argument %s", a)
+		}
+	}
+	if src == "" {
+		return "", opts, fmt.Errorf("missing archive or directory")
+	}
+	return src, opts, nil
+}
+
+// extArgs parses "list|enable|disable [<ext>] [--version=..] [--project=..]".
+func extArgs(args []string) (action, ext string, req pitapi.PHPExtensionRequest, err error) {
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, "--version="):
+			req.Version = strings.TrimPrefix(a, "--version=")
+		case strings.HasPrefix(a, "--project="):
+			req.Project = strings.TrimPrefix(a, "--project=")
+		case strings.HasPrefix(a, "--"):
+			return "", "", req, fmt.Errorf("unknown flag %s", a)
+		case action == "":
+			action = a
+		case ext == "":
+			ext = a
+		default:
+			return "", "", req, fmt.Errorf("unexpected argument %s", a)
+		}
+	}
+
+	switch action {
+	case "list":
+		if req.Project != "" {
+			return "", "", req, fmt.Errorf("list takes --version, not --project")
+		}
+	case "enable", "disable":
+		if ext == "" {
+			return "", "", req, fmt.Errorf("missing extension name")
+		}
+		if req.Project != "" && req.Version != "" {
+			return "", "", req, fmt.Errorf("use --version or --project, not both")
+		}
+	default:
+		return "", "", req, fmt.Errorf("use list, enable or disable")
+	}
+	return action, ext, req, nil
+}
+
+// printPHPExtensions shows one line per extension: on/off for the
+// version, how it is built and which projects load it themselves.
+func printPHPExtensions(version string, list []pitapi.PHPExtension) {
+	fmt.Println("PHP", version, "extensions:")
+	for _, x := range list {
+		state := "off"
+		if x.Enabled {
+			state = "on"
+		}
+		kind := "shared"
+		switch {
+		case x.Builtin:
+			kind = "builtin"
+		case x.Zend:
+			kind = "zend"
+		}
+		line := fmt.Sprintf("  %-20s %-4s %-8s", x.Name, state, kind)
+		if len(x.Projects) > 0 {
+			line += " projects: " + strings.Join(x.Projects, ", ")
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+func printExtensionChange(ext string, on bool, scope string) {
+	if on {
+		fmt.Println("✔ Extension", ext, "enabled for", scope)
+	} else {
+		fmt.Println("✔ Extension", ext, "disabled for", scope)
+	}
+}
+
+func printProjectCreated(base, name, root string, port int) {
+	fmt.Println("  Root:", filepath.Join(base, "projects", name, root))
+	fmt.Println("  Port:", port)
+	fmt.Printf("  URL:  %s://%s\n", urlScheme(base), core.DefaultDomain(name))
+}
+
+// urlScheme is https once `pit setup` has created the local CA.
+func urlScheme(base string) string {
+	if certs.New(base).Exists() {
+		return "https"
+	}
+	return "http"
+}
+
+func printProjectDeleted(name, archivedTo string) {
+	if archivedTo != "" {
+		fmt.Println("✔ Project deleted:", name, "(archived to", archivedTo+")")
+	} else {
+		fmt.Println("✔ Project deleted:", name)
+	}
+}
+
+func printProjectInfo(base string, cfg *core.ProjectConfig) {
+	fmt.Println("Project:", cfg.Name)
+	fmt.Println("PHP Version:", cfg.PHPVersion)
+	fmt.Println("Port:", cfg.Port)
+	fmt.Println("Root:", cfg.Root)
+	if cfg.IsProxy() {
+		fmt.Println("Type: proxy")
+		fmt.Println("Command:", cfg.Command)
+		fmt.Println("Upstream: 127.0.0.1:" + strconv.Itoa(cfg.UpstreamPort))
+	} else {
+		fmt.Println("Framework:", frameworkLabel(base, cfg))
+	}
+
+	if len(cfg.Processes) > 0 {
+		fmt.Println("Processes:")
+		for _, p := range cfg.Processes {
+			fmt.Printf("  %-12s %s\n", p.Name, p.Command)
+		}
+	}
+}
+
+// proxyPatch turns "<upstream-port> <command...>" or "off" into a
+// config patch switching the project type.
+func proxyPatch(args []string) (core.ProjectConfigPatch, error) {
+	var patch core.ProjectConfigPatch
+
+	if args[0] == "off" {
+		typ := core.ProjectTypePHP
+		patch.Type = &typ
+		return patch, nil
+	}
+	if len(args) < 2 {
+		return patch, fmt.Errorf("missing command")
+	}
+
+	upstream, err := strconv.Atoi(args[0])
+	if err != nil {
+		return patch, fmt.Errorf("invalid upstream port: %s", args[0])
+	}
+	typ := core.ProjectTypeProxy
+	command := strings.Join(args[1:], " ")
+	patch.Type = &typ
+	patch.Command = &command
+	patch.UpstreamPort = &upstream
+	return patch, nil
+}
+
+// debugPatch turns "on|off [--mode=...] [--port=...]" into a config
+// patch.
+func debugPatch(args []string) (core.ProjectConfigPatch, error) {
+	var patch core.ProjectConfigPatch
+	var d core.DebugConfig
+
+	switch args[0] {
+	case "on":
+		d.Enabled = true
+	case "off":
+	default:
+		return patch, fmt.Errorf("use on or off, not %q", args[0])
+	}
+
+	for _, a := range args[1:] {
+		switch {
+		case strings.HasPrefix(a, "--mode="):
+			d.Mode = strings.TrimPrefix(a, "--mode=")
+		case strings.HasPrefix(a, "--port="):
+			port, err := strconv.Atoi(strings.TrimPrefix(a, "--port="))
+			if err != nil {
+				return patch, fmt.Errorf("invalid port: %s", a)
+			}
+			d.ClientPort = port
+		default:
+			return patch, fmt.Errorf("unknown flag %s", a)
+		}
+	}
+
+	patch.Debug = &d
+	return patch, nil
+}
+
+// printDebugInfo shows the Xdebug state and, when on, IDE settings
+// ready to paste.
+func printDebugInfo(base string, cfg *core.ProjectConfig) {
+	if cfg.Debug == nil || !cfg.Debug.Enabled {
+		fmt.Println("Xdebug is off for", cfg.Name)
+		return
+	}
+
+	d := cfg.Debug
+	host := cfg.Hostnames()[0]
+	dir := filepath.Join(base, "projects", cfg.Name)
+
+	fmt.Printf("Xdebug is on for %s (mode %s), IDE port %d\n", cfg.Name, d.Mode, d.ClientPort)
+	fmt.Println()
+	fmt.Println("PhpStorm (Settings > PHP > Servers, add):")
+	fmt.Println("  Name:         ", host)
+	fmt.Println("  Host:         ", host)
+	fmt.Println("  Path mapping: ", dir, "->", dir)
+	fmt.Println("  Debug port:   ", d.ClientPort, "(Settings > PHP > Debug)")
+	fmt.Println()
+	fmt.Println("VS Code (.vscode/launch.json, configurations):")
+	fmt.Printf(`  {
+    "name": "pit: %s",
+    "type": "php",
+    "request": "launch",
+    "port": %d,
+    "pathMappings": { "%s": "${workspaceFolder}" }
+  }
+`, cfg.Name, d.ClientPort, dir)
+	if strings.Contains(d.Mode, "profile") || strings.Contains(d.Mode, "trace") {
+		fmt.Println()
+		fmt.Println("Profiles/traces:", filepath.Join(base, "runtime", cfg.Name, "xdebug"))
+	}
+}
+
+func printProxyChange(cfg *core.ProjectConfig) {
+	if !cfg.IsProxy() {
+		fmt.Println("Project", cfg.Name, "is a php project again")
+		return
+	}
+	fmt.Printf("Project %s proxies to 127.0.0.1:%d (%s)\n", cfg.Name, cfg.UpstreamPort, cfg.Command)
+}
+
+// frameworkLabel is "<framework> (pinned|detected)".
+func frameworkLabel(base string, cfg *core.ProjectConfig) string {
+	fw, _ := cfg.Site(base)
+	if cfg.Framework != "" {
+		return fw + " (pinned)"
+	}
+	return fw + " (detected)"
+}
+
+// printProjectDomains lists the hostnames routed to the project by the
+// global nginx, marking aliases.
+func printProjectDomains(base string, hostnames, aliases []string) {
+	scheme := urlScheme(base)
+
+	isAlias := map[string]bool{}
+	for _, a := range aliases {
+		isAlias[a] = true
+	}
+
+	fmt.Println("Domains:")
+	for _, h := range hostnames {
+		if isAlias[h] {
+			fmt.Printf("  %s://%s  (alias)\n", scheme, h)
+		} else {
+			fmt.Printf("  %s://%s\n", scheme, h)
+		}
+	}
+}
+
+// domainPatch turns "add|remove <host> [--alias]" into a config patch
+// against the project's current domains and aliases.
+func domainPatch(domains, aliases []string, args []string) (core.ProjectConfigPatch, error) {
+	var patch core.ProjectConfigPatch
+
+	action, host := args[0], strings.ToLower(strings.TrimSpace(args[1]))
+	list := domains
+	if hasFlag(args[2:], "--alias") {
+		list = aliases
+	}
+
+	var out []string
+	switch action {
+	case "add":
+		out = append(append([]string{}, list...), host)
+	case "remove":
+		found := false
+		for _, h := range list {
+			if h == host {
+				found = true
+				continue
+			}
+			out = append(out, h)
+		}
+		if !found {
+			return patch, fmt.Errorf("%s is not configured", host)
+		}
+		if out == nil {
+			out = []string{}
+		}
+	default:
+		return patch, fmt.Errorf("unknown domain action %q (use add or remove)", action)
+	}
+
+	if hasFlag(args[2:], "--alias") {
+		patch.Aliases = &out
+	} else {
+		patch.Domains = &out
+	}
+	return patch, nil
+}
+
+func printProjectStatus(name string, statuses map[string]pitapi.ServiceStatus) {
+	names := make([]string, 0, len(statuses))
+	for n := range statuses {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	fmt.Println("Project:", name)
+	for _, n := range names {
+		st := statuses[n]
+		if st.Schedule != "" {
+			continue
+		}
+		state := "stopped"
+		if st.Running {
+			state = "running"
+		}
+		fmt.Printf("  %-28s %-8s pid=%-7d port=%d\n", n, state, st.PID, st.Port)
+	}
+	printCronStatus(statuses)
+}
+
+// printCronStatus lists the cron:<job> entries of a project status and
+// reports whether there were any.
+func printCronStatus(statuses map[string]pitapi.ServiceStatus) bool {
+	var jobs []string
+	for n, st := range statuses {
+		if st.Schedule != "" {
+			jobs = append(jobs, n)
+		}
+	}
+	if len(jobs) == 0 {
+		return false
+	}
+	sort.Strings(jobs)
+
+	fmt.Println("Cron:")
+	for _, n := range jobs {
+		st := statuses[n]
+
+		last, exit := "never", "-"
+		if st.LastRun != nil {
+			last = st.LastRun.Local().Format("2006-01-02 15:04")
+			exit = strconv.Itoa(*st.ExitCode)
+		}
+		if st.Running {
+			exit = fmt.Sprintf("running (pid %d)", st.PID)
+		}
+		next := "-"
+		if st.NextRun != nil {
+			next = st.NextRun.Local().Format("2006-01-02 15:04")
+		}
+
+		fmt.Printf("  %-20s %-16s last=%-16s exit=%-6s next=%s\n",
+			strings.TrimPrefix(n, "cron:"), st.Schedule, last, exit, next)
+	}
+	return true
+}
+
 func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  pit setup")
-	fmt.Println("  pit start")
+	fmt.Println("  pit start [-d]")
 	fmt.Println("  pit stop")
+	fmt.Println("  pit status")
+	fmt.Println("  pit logs [-f] [-n <lines>]")
+	fmt.Println("  pit events [--type=<prefix>] [--project=<name>] [--json]")
 	fmt.Println("  pit api")
 	fmt.Println("  pit php use <version>")
 	fmt.Println("  pit php versions")
 	fmt.Println("  pit php current")
+	fmt.Println("  pit php install <archive.tar.gz|dir> [--as=<version>] [--no-verify]")
+	fmt.Println("  pit php remove <version>")
+	fmt.Println("  pit php ext list [--version=<v>]")
+	fmt.Println("  pit php ext enable|disable <ext> [--version=<v>|--project=<name>]")
+	fmt.Println("  pit nginx install <archive.tar.gz|dir> [--no-verify]")
+	fmt.Println("  pit nginx remove")
 	fmt.Println("  pit project list")
+	fmt.Println("  pit project create <name>")
+	fmt.Println("  pit project delete <name> [--archive]")
+	fmt.Println("  pit project start <name>")
+	fmt.Println("  pit project stop <name>")
+	fmt.Println("  pit project status <name>")
 	fmt.Println("  pit project info <name>")
 	fmt.Println("  pit project set-port <name> <port>")
+	fmt.Println("  pit project domain <name> <add|remove> <host> [--alias]")
+	fmt.Println("  pit project framework <name> [<framework>|auto]")
+	fmt.Println("  pit project set-root <name> <dir>")
+	fmt.Println("  pit project proxy <name> <upstream-port> <command...>")
+	fmt.Println("  pit project proxy <name> off")
+	fmt.Println("  pit project cron <name>")
+	fmt.Println("  pit project debug <name> [on|off] [--mode=debug,profile,coverage] [--port=<n>]")
 	fmt.Println("  pit project restart <name>")
 	fmt.Println("  pit tools sync")
+	fmt.Println("  pit templates list")
+	fmt.Println("  pit templates export <name>")
 }
 
 func printPHPUsage() {
@@ -337,12 +1211,24 @@ func printPHPUsage() {
 	fmt.Println("  pit php use <version>")
 	fmt.Println("  pit php versions")
 	fmt.Println("  pit php current")
+	fmt.Println("  pit php install <archive.tar.gz|dir> [--as=<version>] [--no-verify]")
+	fmt.Println("  pit php remove <version>")
+	fmt.Println("  pit php ext list [--version=<v>]")
+	fmt.Println("  pit php ext enable|disable <ext> [--version=<v>|--project=<name>]")
 }
 
 func printProjectUsage() {
 	fmt.Println("Project Commands:")
 	fmt.Println("  pit project list")
+	fmt.Println("  pit project create <name>")
+	fmt.Println("  pit project delete <name> [--archive]")
+	fmt.Println("  pit project start <name>")
+	fmt.Println("  pit project stop <name>")
+	fmt.Println("  pit project status <name>")
 	fmt.Println("  pit project info <name>")
 	fmt.Println("  pit project set-port <name> <port>")
+	fmt.Println("  pit project domain <name> <add|remove> <host> [--alias]")
+	fmt.Println("  pit project framework <name> [<framework>|auto]")
+	fmt.Println("  pit project set-root <name> <dir>")
 	fmt.Println("  pit project restart <name>")
 }
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"pit/internal/api"
+	"pit/internal/config"
+	"pit/internal/core"
+	"pit/pkg/pitapi"
+	"pit/pkg/pitclient"
+)
+
+////////////////////////////////////////////////////////
+// DAEMON CLIENT
+////////////////////////////////////////////////////////
+
+// runRemote executes the command through the running daemon's control
+// socket. It returns false for commands that must run locally (setup,
+// logs, api, ...).
+func runRemote(base string) bool {
+	c := pitclient.NewUnix(api.ControlSocketPath(base))
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "start":
+		st, err := c.Status(ctx)
+		if err != nil {
+			fmt.Println("pit already running")
+		} else {
+			fmt.Printf("pit already running (pid %d)\n", st.PID)
+		}
+		os.Exit(1)
+
+	case "stop":
+		if err := remoteStop(ctx, c, base); err != nil {
+			fmt.Println("Error stopping engine:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✔ Engine stopped")
+		os.Exit(0)
+
+	case "status":
+		st, err := c.Status(ctx)
+		if err != nil {
+			fmt.Println("Error querying daemon:", err)
+			os.Exit(1)
+		}
+		printEngineStatus(st)
+
+	case "events":
+		remoteEvents(c)
+
+	case "php":
+		return remotePHP(ctx, c)
+
+	case "project":
+		return remoteProject(ctx, c, base)
+
+	case "tools":
+		if len(os.Args) < 3 || os.Args[2] != "sync" {
+			return false
+		}
+		fmt.Println("[Tools] Scanning tools...")
+		if err := c.SyncTools(ctx); err != nil {
+			fmt.Println("Tools sync failed:", err)
+			return true
+		}
+		fmt.Println("✔ Tools synced successfully")
+
+	default:
+		return false
+	}
+	return true
+}
+
+// remoteStop asks the daemon to tear down and waits for it to release
+// its pid lock.
+func remoteStop(ctx context.Context, c *pitclient.Client, base string) error {
+	if err := c.StopEngine(ctx); err != nil {
+		return err
+	}
+
+	cfg := config.Load(filepath.Join(base, "config", "engine.json"))
+	wait := 10 * time.Second
+	if cfg.StopTimeout > 0 {
+		wait = time.Duration(cfg.StopTimeout) * time.Second
+	}
+	wait += 5 * time.Second
+
+	pidFile := filepath.Join(base, "runtime", "pit.pid")
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		if !core.LockHeld(pidFile) {
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("daemon still running after %s", wait)
+}
+
+func remotePHP(ctx context.Context, c *pitclient.Client) bool {
+	if len(os.Args) < 3 {
+		return false
+	}
+
+	switch os.Args[2] {
+	case "use":
+		if len(os.Args) < 4 {
+			return false
+		}
+		ver := os.Args[3]
+		if err := c.UsePHPVersion(ctx, ver); err != nil {
+			fmt.Println("Error setting PHP version:", err)
+			return true
+		}
+		fmt.Println("PHP version switched to", ver)
+
+	case "versions":
+		versions, err := c.PHPVersions(ctx)
+		if err != nil {
+			fmt.Println("Error listing versions:", err)
+			return true
+		}
+		fmt.Println("Available PHP versions:", versions)
+
+	case "current":
+		ver, err := c.PHPVersion(ctx)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		fmt.Println("Current PHP version:", ver)
+
+	case "ext":
+		action, ext, req, err := extArgs(os.Args[3:])
+		if err != nil {
+			return false
+		}
+
+		if action == "list" {
+			resp, err := c.PHPExtensions(ctx, req.Version)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return true
+			}
+			printPHPExtensions(resp.Version, resp.Extensions)
+			return true
+		}
+
+		set := c.DisablePHPExtension
+		if action == "enable" {
+			set = c.EnablePHPExtension
+		}
+		if _, err := set(ctx, ext, req); err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		scope := "project " + req.Project
+		if req.Project == "" {
+			scope = "PHP " + req.Version
+			if req.Version == "" {
+				scope = "the current PHP"
+			}
+		}
+		printExtensionChange(ext, action == "enable", scope)
+
+	default:
+		return false
+	}
+	return true
+}
+
+func remoteProject(ctx context.Context, c *pitclient.Client, base string) bool {
+	if len(os.Args) < 3 {
+		return false
+	}
+
+	if os.Args[2] == "list" {
+		projects, err := c.Projects(ctx)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		for _, p := range projects {
+			fmt.Println("-", p)
+		}
+		return true
+	}
+
+	// everything else takes a project name; let the local handler
+	// print usage when it is missing
+	if len(os.Args) < 4 {
+		return false
+	}
+	name := os.Args[3]
+
+	switch os.Args[2] {
+	case "create":
+		cfg, err := c.CreateProject(ctx, name)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✔ Project created:", name)
+		printProjectCreated(base, name, cfg.Root, cfg.Port)
+
+	case "delete":
+		dst, err := c.DeleteProject(ctx, name, hasFlag(os.Args[4:], "--archive"))
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		printProjectDeleted(name, dst)
+
+	case "start":
+		if _, err := c.StartProject(ctx, name); err != nil {
+			fmt.Println("Start failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Project started:", name)
+
+	case "stop":
+		if _, err := c.StopProject(ctx, name); err != nil {
+			fmt.Println("Stop failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Project stopped:", name)
+
+	case "restart":
+		if _, err := c.RestartProject(ctx, name); err != nil {
+			fmt.Println("Restart failed:", err)
+			return true
+		}
+		fmt.Println("Project restarted:", name)
+
+	case "status":
+		statuses, err := c.ProjectStatus(ctx, name)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		printProjectStatus(name, statuses)
+
+	case "debug":
+		// showing the state reads the local config
+		if len(os.Args) < 5 {
+			return false
+		}
+		patch, err := debugPatch(os.Args[4:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		cfg, err := c.UpdateProject(ctx, name, patch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		printDebugInfo(base, localConfig(cfg))
+
+	case "cron":
+		statuses, err := c.ProjectStatus(ctx, name)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if !printCronStatus(statuses) {
+			fmt.Println("No cron jobs configured for", name)
+			return true
+		}
+		fmt.Println("Log:", filepath.Join(base, "runtime", name, "logs", "cron.log"))
+
+	case "info":
+		cfg, err := c.Project(ctx, name)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		printProjectInfo(base, localConfig(cfg))
+		printProjectDomains(base, localConfig(cfg).Hostnames(), cfg.Aliases)
+
+	case "domain":
+		if len(os.Args) < 6 {
+			return false
+		}
+		cfg, err := c.Project(ctx, name)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		patch, err := domainPatch(cfg.Domains, cfg.Aliases, os.Args[4:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		cfg, err = c.UpdateProject(ctx, name, patch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Project", name, "domains updated")
+		printProjectDomains(base, localConfig(cfg).Hostnames(), cfg.Aliases)
+
+	case "framework", "set-root":
+		// showing the framework reads local files; only changes go
+		// through the daemon
+		if len(os.Args) < 5 {
+			return false
+		}
+		val := os.Args[4]
+		var patch pitapi.ProjectConfigPatch
+		if os.Args[2] == "framework" {
+			patch.Framework = &val
+		} else {
+			patch.Root = &val
+		}
+		cfg, err := c.UpdateProject(ctx, name, patch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if os.Args[2] == "framework" {
+			fmt.Println("Project", name, "framework:", frameworkLabel(base, localConfig(cfg)))
+		} else {
+			fmt.Println("Project", name, "document root:", cfg.Root)
+		}
+
+	case "proxy":
+		if len(os.Args) < 5 {
+			return false
+		}
+		patch, err := proxyPatch(os.Args[4:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		cfg, err := c.UpdateProject(ctx, name, patch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		printProxyChange(localConfig(cfg))
+
+	case "set-port":
+		if len(os.Args) < 5 {
+			return false
+		}
+		port, err := strconv.Atoi(os.Args[4])
+		if err != nil {
+			fmt.Println("Invalid port:", os.Args[4])
+			return true
+		}
+		if _, err := c.SetProjectPort(ctx, name, port); err != nil {
+			fmt.Println("Error:", err)
+			return true
+		}
+		fmt.Println("Project", name, "updated to port", port)
+
+	default:
+		return false
+	}
+	return true
+}
+
+// localConfig converts an API config for the shared printers.
+func localConfig(cfg *pitapi.ProjectConfig) *core.ProjectConfig {
+	return &core.ProjectConfig{
+		Name:       cfg.Name,
+		PHPVersion: cfg.PHPVersion,
+		Port:       cfg.Port,
+		Root:       cfg.Root,
+		Framework:  cfg.Framework,
+		Domains:    cfg.Domains,
+		Aliases:    cfg.Aliases,
+
+		Type:         cfg.Type,
+		Command:      cfg.Command,
+		UpstreamPort: cfg.UpstreamPort,
+		Env:          cfg.Env,
+		EnvFiles:     cfg.EnvFiles,
+		Processes:    cfg.Processes,
+		Cron:         cfg.Cron,
+		PHPIni:       cfg.PHPIni,
+		Pool:         cfg.Pool,
+		Extensions:   cfg.Extensions,
+		Debug:        cfg.Debug,
+	}
+}
+
+////////////////////////////////////////////////////////
+// pit events
+////////////////////////////////////////////////////////
+
+// remoteEvents follows the daemon's event stream until interrupted.
+//
+//	pit events [--type=service.,php.] [--project=<name>] [--json]
+func remoteEvents(c *pitclient.Client) {
+	var f pitclient.EventFilter
+	raw := false
+	for _, a := range os.Args[2:] {
+		switch {
+		case a == "--json":
+			raw = true
+		case strings.HasPrefix(a, "--type="):
+			f.Types = strings.Split(strings.TrimPrefix(a, "--type="), ",")
+		case strings.HasPrefix(a, "--project="):
+			f.Project = strings.TrimPrefix(a, "--project=")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	stream, err := c.Events(ctx, f)
+	if err != nil {
+		fmt.Println("Error connecting to daemon:", err)
+		os.Exit(1)
+	}
+	defer stream.Close()
+
+	for {
+		ev, err := stream.Next()
+		if err != nil {
+			if ctx.Err() == nil {
+				if errors.Is(err, io.EOF) {
+					fmt.Println("event stream closed by daemon")
+				} else {
+					fmt.Println("event stream error:", err)
+				}
+			}
+			return
+		}
+
+		if raw {
+			out, _ := json.Marshal(ev)
+			fmt.Println(string(out))
+			continue
+		}
+		printEvent(ev)
+	}
+}
+
+func printEvent(ev pitapi.Event) {
+	subject := ev.Service
+	if ev.Project != "" {
+		if subject != "" {
+			subject = ev.Project + "/" + subject
+		} else {
+			subject = ev.Project
+		}
+	}
+
+	line := fmt.Sprintf("%s  %-24s %s", ev.Time.Local().Format("15:04:05"), ev.Type, subject)
+	if ev.Message != "" {
+		line += "  " + ev.Message
+	}
+	for _, k := range sortedKeys(ev.Data) {
+		line += fmt.Sprintf(" %s=%v", k, ev.Data[k])
+	}
+	fmt.Println(strings.TrimRight(line, " "))
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pit/internal/core"
+	"pit/internal/services"
+	"pit/pkg/pitapi"
+)
+
+// ================================
+// V2 ERRORS
+// ================================
+
+// apiError is an error that already knows its HTTP status and code.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+func badRequest(code, msg string) error {
+	return &apiError{Status: http.StatusBadRequest, Code: code, Message: msg}
+}
+
+// classify maps core errors to HTTP status + code.
+func classify(err error) (int, string) {
+	var ae *apiError
+	switch {
+	case errors.As(err, &ae):
+		return ae.Status, ae.Code
+	case errors.As(err, new(*services.NginxConfigError)):
+		return http.StatusUnprocessableEntity, pitapi.CodeInvalidConfig
+	case errors.Is(err, core.ErrProjectNotFound):
+		return http.StatusNotFound, pitapi.CodeProjectNotFound
+	case errors.Is(err, core.ErrProjectExists):
+		return http.StatusConflict, pitapi.CodeProjectExists
+	case errors.Is(err, core.ErrInvalidProjectName):
+		return http.StatusBadRequest, pitapi.CodeInvalidName
+	case errors.Is(err, core.ErrInvalidPort):
+		return http.StatusBadRequest, pitapi.CodeInvalidPort
+	case errors.Is(err, core.ErrPortConflict):
+		return http.StatusConflict, pitapi.CodePortConflict
+	case errors.Is(err, core.ErrPortInUse):
+		return http.StatusConflict, pitapi.CodePortInUse
+	case errors.Is(err, core.ErrInvalidDomain):
+		return http.StatusBadRequest, pitapi.CodeInvalidDomain
+	case errors.Is(err, core.ErrDomainConflict):
+		return http.StatusConflict, pitapi.CodeDomainConflict
+	case errors.Is(err, core.ErrInvalidFramework):
+		return http.StatusBadRequest, pitapi.CodeInvalidFramework
+	case errors.Is(err, core.ErrInvalidRoot):
+		return http.StatusBadRequest, pitapi.CodeInvalidRoot
+	case errors.Is(err, core.ErrInvalidConfig):
+		return http.StatusBadRequest, pitapi.CodeInvalidProject
+	case errors.Is(err, core.ErrNoFreePort):
+		return http.StatusServiceUnavailable, pitapi.CodeNoFreePort
+	case errors.Is(err, core.ErrPHPVersionNotFound):
+		return http.StatusNotFound, pitapi.CodePHPVersionNotFound
+	case errors.Is(err, core.ErrExtensionNotFound):
+		return http.StatusNotFound, pitapi.CodeExtensionNotFound
+	case errors.Is(err, core.ErrExtensionLocked):
+		return http.StatusConflict, pitapi.CodeExtensionLocked
+	case errors.Is(err, core.ErrAlreadyRunning):
+		return http.StatusConflict, pitapi.CodeEngineRunning
+	default:
+		return http.StatusInternalServerError, pitapi.CodeInternal
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, code := classify(err)
+	body := pitapi.ErrorBody{Code: code, Message: err.Error()}
+
+	var cfgErr *services.NginxConfigError
+	if errors.As(err, &cfgErr) {
+		body.Issues = cfgErr.Issues
+	}
+	writeJSONStatus(w, status, pitapi.ErrorEnvelope{Error: body})
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"pit/internal/events"
+)
+
+// ================================
+// EVENT STREAM (SSE)
+// ================================
+//
+//	GET /events?type=service.,php.&project=blog
+//
+// Each event is sent as
+//
+//	id: 42
+//	event: service.crashed
+//	data: {"id":42,"type":"service.crashed",...}
+//
+// "type" filters by comma-separated prefixes, "project" by project
+// name. A comment line is sent every sseHeartbeat so idle proxies and
+// clients notice dead connections.
+
+const sseHeartbeat = 15 * time.Second
+
+func eventsHandler(bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		match := eventFilter(r)
+
+		sub := bus.Subscribe(256)
+		defer sub.Close()
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, "retry: 3000\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+
+		var dropped uint64
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case <-heartbeat.C:
+				if n := sub.Dropped(); n != dropped {
+					fmt.Fprintf(w, ": %d events dropped (client too slow)\n\n", n-dropped)
+					dropped = n
+				} else {
+					fmt.Fprint(w, ": ping\n\n")
+				}
+				flusher.Flush()
+
+			case ev, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if !match(ev) {
+					continue
+				}
+				data, err := json.Marshal(ev)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func eventFilter(r *http.Request) func(events.Event) bool {
+	q := r.URL.Query()
+	project := q.Get("project")
+
+	var prefixes []string
+	for _, p := range strings.Split(q.Get("type"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+
+	return func(ev events.Event) bool {
+		if project != "" && ev.Project != project {
+			return false
+		}
+		if len(prefixes) == 0 {
+			return true
+		}
+		for _, p := range prefixes {
+			if strings.HasPrefix(string(ev.Type), p) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"pit/internal/core"
+	"pit/internal/events"
 )
 
 func RegisterRoutes(mux *http.ServeMux, engine *core.Engine) {
@@ -16,6 +17,18 @@ func RegisterRoutes(mux *http.ServeMux, engine *core.Engine) {
 		writeJSON(w, engine.ServiceStatuses())
 	})
 
+	// ================================
+	// FULL STATUS (global + projects)
+	// ================================
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, engine.FullStatus())
+	})
+
+	// ================================
+	// LIVE EVENTS (SSE)
+	// ================================
+	mux.HandleFunc("GET /events", eventsHandler(events.Default))
+
 	// ================================
 	// START ALL SERVICES
 	// ================================
@@ -40,6 +53,21 @@ func RegisterRoutes(mux *http.ServeMux, engine *core.Engine) {
 		writeJSON(w, map[string]string{"status": "ok"})
 	})
 
+	// ================================
+	// SYNC TOOLS
+	// ================================
+	mux.HandleFunc("/tools/sync", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err := engine.SyncTools(); err != nil {
+			writeJSON(w, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, map[string]string{"status": "ok"})
+	})
+
 	// ================================
 	// GET CURRENT PHP VERSION
 	// ================================
@@ -87,6 +115,8 @@ func RegisterRoutes(mux *http.ServeMux, engine *core.Engine) {
 			return
 		}
 
+		engine.ForceKillAllProjectRuntimes()
+
 		if err := engine.SetPHPVersion(version); err != nil {
 			writeJSON(w, map[string]string{"error": err.Error()})
 			return
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pit/pkg/pitapi"
+)
+
+// ================================
+// OPENAPI 3 (generated)
+// ================================
+//
+// Built once from the v2 route table; schemas come from the pkg/pitapi
+// structs via reflection, so the document follows the code.
+
+var pathParamRe = regexp.MustCompile(`\{([^}]+)\}`)
+
+func openAPIHandler(routes []route) http.HandlerFunc {
+	doc, err := json.MarshalIndent(buildOpenAPI(routes), "", "  ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(doc)
+	}
+}
+
+func buildOpenAPI(routes []route) map[string]any {
+	g := &schemaGen{schemas: map[string]any{}}
+	errRef := g.schema(reflect.TypeOf(pitapi.ErrorEnvelope{}))
+
+	paths := map[string]map[string]any{}
+	for _, rt := range routes {
+		op := map[string]any{
+			"operationId": rt.OperationID,
+			"summary":     rt.Summary,
+			"tags":        []string{rt.Tag},
+		}
+
+		var params []map[string]any
+		for _, m := range pathParamRe.FindAllStringSubmatch(rt.Path, -1) {
+			params = append(params, map[string]any{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, q := range rt.Query {
+			params = append(params, map[string]any{
+				"name": q.Name, "in": "query", "description": q.Description,
+				"schema": map[string]any{"type": q.Type},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if rt.Body != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(rt.Body))},
+				},
+			}
+		}
+
+		status := rt.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		mime := "application/json"
+		if rt.Stream {
+			mime = "text/event-stream"
+		}
+		ok := map[string]any{"description": http.StatusText(status)}
+		if rt.Response != nil {
+			ok["content"] = map[string]any{
+				mime: map[string]any{"schema": g.schema(reflect.TypeOf(rt.Response))},
+			}
+		}
+		op["responses"] = map[string]any{
+			strconv.Itoa(status): ok,
+			"default": map[string]any{
+				"description": "error",
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": errRef},
+				},
+			},
+		}
+
+		if paths[rt.Path] == nil {
+			paths[rt.Path] = map[string]any{}
+		}
+		paths[rt.Path][strings.ToLower(rt.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "pit API",
+			"version":     "2",
+			"description": "Served on runtime/pit.sock (no auth) and, when api.tcp is set, on loopback TCP with a bearer token.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []map[string]any{{}, {"bearerAuth": []string{}}},
+	}
+}
+
+// ----------------------------
+// JSON SCHEMA FROM GO TYPES
+// ----------------------------
+
+type schemaGen struct {
+	schemas map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if _, isRef := s["$ref"]; !isRef {
+			s["nullable"] = true
+		}
+		return s
+
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, done := g.schemas[t.Name()]; !done {
+			g.schemas[t.Name()] = map[string]any{} // break cycles
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+
+	case reflect.String:
+		return map[string]any{"type": "string"}
+
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+
+	default: // interface{}
+		return map[string]any{}
+	}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
@@ -110,16 +110,8 @@ func (h *ProjectHandler) Register(mux *http.ServeMux) {
 	// ========================
 	mux.HandleFunc("/project/restart", func(w http.ResponseWriter, r *http.Request) {
 		name := r.URL.Query().Get("name")
-		engine, err := h.Registry.Load(name)
-		if err != nil {
-			writeJSON(w, map[string]string{"error": err.Error()})
-			return
-		}
-
-		_ = engine.Stop()
-		err = engine.Start()
 
-		if err != nil {
+		if err := h.Registry.Restart(name); err != nil {
 			writeJSON(w, map[string]string{"error": err.Error()})
 			return
 		}
@@ -169,43 +161,21 @@ func (h *ProjectHandler) Register(mux *http.ServeMux) {
 			return
 		}
 
-		// load config existing
-		cfg, err := h.Registry.ReadConfig(name)
-		if err != nil {
-			writeJSON(w, map[string]string{"error": "project not found"})
-			return
-		}
-
-		// read JSON payload
-		var req struct {
-			Port       *int   `json:"port"`        // optional
-			PHPVersion string `json:"php_version"` // optional
-		}
+		// read JSON payload (port / php_version, both optional)
+		var req core.ProjectConfigPatch
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			writeJSON(w, map[string]string{"error": "invalid json"})
 			return
 		}
 
-		// apply updates
-		if req.Port != nil {
-			cfg.Port = *req.Port
-		}
-		if req.PHPVersion != "" {
-			cfg.PHPVersion = req.PHPVersion
-		}
-
-		// save config
-		if err := cfg.Save(h.BasePath); err != nil {
+		// validate, save, restart runtime
+		cfg, err := h.Registry.Update(name, req)
+		if err != nil {
 			writeJSON(w, map[string]string{"error": err.Error()})
 			return
 		}
 
-		// restart runtime
-		engine, _ := h.Registry.Load(name)
-		_ = engine.Stop()
-		_ = engine.Start()
-
 		writeJSON(w, map[string]any{
 			"status": "updated",
 			"config": cfg,
@@ -236,23 +206,11 @@ func (h *ProjectHandler) Register(mux *http.ServeMux) {
 			return
 		}
 
-		cfg, err := h.Registry.ReadConfig(name)
-		if err != nil {
-			writeJSON(w, map[string]string{"error": "project not found"})
-			return
-		}
-
-		cfg.Port = port
-
-		if err := cfg.Save(h.BasePath); err != nil {
+		if _, err := h.Registry.Update(name, core.ProjectConfigPatch{Port: &port}); err != nil {
 			writeJSON(w, map[string]string{"error": err.Error()})
 			return
 		}
 
-		engine, _ := h.Registry.Load(name)
-		_ = engine.Stop()
-		_ = engine.Start()
-
 		writeJSON(w, map[string]any{
 			"status":  "port-updated",
 			"project": name,
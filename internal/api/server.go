@@ -1,22 +1,217 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	"pit/internal/core"
+	"pit/pkg/pitapi"
 )
 
-func StartAPIServer(engine *core.Engine) {
+// Server bundles the listeners of the pit API: the control socket
+// (always) and the loopback TCP listener (only when api.tcp is set in
+// config/engine.json).
+type Server struct {
+	Control *http.Server
+	TCP     *http.Server
+
+	sock string
+
+	// cancelled on Shutdown so long-lived streams (/events) return
+	cancel context.CancelFunc
+}
+
+// Handler builds the API routes.
+func Handler(engine *core.Engine) http.Handler {
 	mux := http.NewServeMux()
 
-	// register engine routes
+	// v1: legacy routes, kept as a compatibility shim for old clients
 	RegisterRoutes(mux, engine)
 
-	// NEW: project routes
 	projectHandler := NewProjectHandler(engine.BasePath)
 	projectHandler.Register(mux)
 
-	fmt.Println("pit API running at http://localhost:7070")
-	http.ListenAndServe(":7070", mux)
+	// v2: method routing, status codes, error envelope
+	RegisterV2(mux, engine)
+
+	return mux
+}
+
+// NewServer prepares (but does not start) the API listeners.
+func NewServer(engine *core.Engine) (*Server, error) {
+	h := Handler(engine)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	base := func(net.Listener) context.Context { return ctx }
+
+	s := &Server{
+		Control: &http.Server{Handler: h, BaseContext: base},
+		sock:    ControlSocketPath(engine.BasePath),
+		cancel:  cancel,
+	}
+
+	cfg := engine.Config.API
+	if !cfg.TCP {
+		return s, nil
+	}
+
+	addr, err := loopbackAddr(cfg.Listen)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	token, err := engine.APIToken()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("api token: %w", err)
+	}
+
+	s.TCP = &http.Server{
+		Addr:        addr,
+		Handler:     requireToken(token, h),
+		BaseContext: base,
+	}
+	return s, nil
+}
+
+// Serve starts every listener in the background; failures go to errc.
+func (s *Server) Serve(errc chan<- error) {
+	ln, err := ListenControl(s.sock)
+	if err != nil {
+		errc <- fmt.Errorf("control socket: %w", err)
+		return
+	}
+
+	go func() {
+		fmt.Println("✔ Control socket at", s.sock)
+		if err := s.Control.Serve(ln); err != nil && err != http.ErrServerClosed {
+			errc <- err
+		}
+	}()
+
+	if s.TCP == nil {
+		return
+	}
+
+	go func() {
+		fmt.Println("✔ API started on http://" + s.TCP.Addr + " (token required)")
+		if err := s.TCP.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errc <- err
+		}
+	}()
+}
+
+// Shutdown stops the listeners gracefully and removes the socket.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancel()
+
+	var first error
+	for _, srv := range []*http.Server{s.Control, s.TCP} {
+		if srv == nil {
+			continue
+		}
+		if err := srv.Shutdown(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	_ = os.Remove(s.sock)
+	return first
+}
+
+// ControlSocketPath is where a running engine serves the same API for
+// the local CLI (pit status, ...).
+func ControlSocketPath(base string) string {
+	return filepath.Join(base, "runtime", "pit.sock")
+}
+
+// ListenControl binds the control socket, replacing a stale one left by
+// an engine that did not shut down cleanly. The socket is created with
+// mode 0600: only the user running pit can talk to it.
+func ListenControl(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("control socket %s is in use", path)
+		}
+		_ = os.Remove(path)
+	}
+
+	old := syscall.Umask(0o077)
+	ln, err := net.Listen("unix", path)
+	syscall.Umask(old)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// loopbackAddr refuses anything but a loopback bind address.
+func loopbackAddr(listen string) (string, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", fmt.Errorf("invalid api.listen %q: %w", listen, err)
+	}
+	if host == "localhost" {
+		host = "127.0.0.1"
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsLoopback() {
+		return "", fmt.Errorf("api.listen must be a loopback address, got %q", listen)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// requireToken guards the TCP listener: "Authorization: Bearer <token>"
+// or "X-Pit-Token: <token>".
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Pit-Token")
+		if auth := r.Header.Get("Authorization"); got == "" && strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pit"`)
+			if strings.HasPrefix(r.URL.Path, "/v2/") {
+				writeJSONStatus(w, http.StatusUnauthorized, pitapi.ErrorEnvelope{
+					Error: pitapi.ErrorBody{Code: pitapi.CodeUnauthorized, Message: "missing or invalid API token"},
+				})
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			writeJSON(w, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StartAPIServer serves the API in the foreground (pit api).
+func StartAPIServer(engine *core.Engine) {
+	srv, err := NewServer(engine)
+	if err != nil {
+		fmt.Println("API server error:", err)
+		return
+	}
+
+	errc := make(chan error, 2)
+	srv.Serve(errc)
+
+	fmt.Println("API server error:", <-errc)
+	_ = srv.Shutdown(context.Background())
 }
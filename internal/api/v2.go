@@ -0,0 +1,495 @@
+package api
+
+import (
+	"net/http"
+
+	"pit/internal/core"
+	"pit/internal/events"
+	"pit/pkg/pitapi"
+)
+
+// ================================
+// API V2
+// ================================
+//
+// Method + path routing (Go 1.22 ServeMux patterns), JSON bodies,
+// proper status codes and the ErrorEnvelope on failure. The legacy
+// routes in handler.go / project_handler.go stay as a v1 shim.
+
+type v2Handler struct {
+	engine   *core.Engine
+	registry *core.ProjectRegistry
+}
+
+func RegisterV2(mux *http.ServeMux, engine *core.Engine) {
+	h := &v2Handler{
+		engine:   engine,
+		registry: core.NewProjectRegistry(engine.BasePath),
+	}
+
+	routes := h.routes()
+	spec := openAPIHandler(routes)
+
+	v2 := http.NewServeMux()
+	for _, rt := range routes {
+		v2.HandleFunc(rt.Method+" "+rt.Path, rt.Handler)
+	}
+	v2.HandleFunc("GET /v2/openapi.json", spec)
+
+	mux.Handle("/v2/", withJSONFallback(v2))
+	mux.HandleFunc("GET /openapi.json", spec)
+}
+
+// route describes one v2 endpoint. The same table registers the
+// handlers and generates /openapi.json.
+type route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Tag         string
+	Summary     string
+
+	Query    []queryParam
+	Body     any // zero value of the JSON request body, nil = none
+	Status   int // success status (default 200)
+	Response any // zero value of the JSON response body
+	Stream   bool
+
+	Handler http.HandlerFunc
+}
+
+type queryParam struct {
+	Name        string
+	Type        string // string | boolean
+	Description string
+}
+
+func (h *v2Handler) routes() []route {
+	type services = map[string]pitapi.ServiceStatus
+
+	return []route{
+		// engine
+		{Method: "GET", Path: "/v2/status", OperationID: "getStatus", Tag: "engine",
+			Summary: "Engine, service and project status", Response: pitapi.EngineStatus{}, Handler: h.status},
+		{Method: "GET", Path: "/v2/services", OperationID: "listServices", Tag: "engine",
+			Summary: "Status of the global services", Response: services{}, Handler: h.services},
+		{Method: "GET", Path: "/v2/events", OperationID: "streamEvents", Tag: "engine",
+			Summary: "Server-Sent Events stream of state changes", Stream: true, Response: pitapi.Event{},
+			Query: []queryParam{
+				{Name: "type", Type: "string", Description: "comma-separated event type prefixes"},
+				{Name: "project", Type: "string", Description: "only events of this project"},
+			},
+			Handler: eventsHandler(events.Default)},
+		{Method: "POST", Path: "/v2/engine/start", OperationID: "startEngine", Tag: "engine",
+			Summary: "Start the global services", Response: pitapi.StatusResponse{}, Handler: h.engineStart},
+		{Method: "POST", Path: "/v2/engine/stop", OperationID: "stopEngine", Tag: "engine",
+			Summary: "Stop everything and shut the engine down", Response: pitapi.StatusResponse{}, Handler: h.engineStop},
+		{Method: "POST", Path: "/v2/engine/reload", OperationID: "reloadEngine", Tag: "engine",
+			Summary: "Re-read config/engine.json and reload nginx", Response: pitapi.StatusResponse{}, Handler: h.engineReload},
+
+		// php
+		{Method: "GET", Path: "/v2/php/versions", OperationID: "listPHPVersions", Tag: "php",
+			Summary: "Installed PHP versions", Response: pitapi.PHPVersionsResponse{}, Handler: h.phpVersions},
+		{Method: "GET", Path: "/v2/php/current", OperationID: "getPHPVersion", Tag: "php",
+			Summary: "PHP version of the global PHP-FPM", Response: pitapi.PHPVersionResponse{}, Handler: h.phpCurrent},
+		{Method: "PUT", Path: "/v2/php/current", OperationID: "usePHPVersion", Tag: "php",
+			Summary: "Switch the global PHP version", Body: pitapi.PHPVersionRequest{}, Response: pitapi.PHPVersionResponse{}, Handler: h.phpUse},
+		{Method: "GET", Path: "/v2/php/extensions", OperationID: "listPHPExtensions", Tag: "php",
+			Summary: "Extensions of a PHP build and where they are enabled", Response: pitapi.PHPExtensionsResponse{},
+			Query:   []queryParam{{Name: "version", Type: "string", Description: "PHP version (default: current)"}},
+			Handler: h.phpExtensions},
+		{Method: "POST", Path: "/v2/php/extensions/{ext}/enable", OperationID: "enablePHPExtension", Tag: "php",
+			Summary: "Enable an extension for a PHP version or one project", Body: pitapi.PHPExtensionRequest{}, Response: pitapi.PHPExtension{}, Handler: h.phpExtensionEnable},
+		{Method: "POST", Path: "/v2/php/extensions/{ext}/disable", OperationID: "disablePHPExtension", Tag: "php",
+			Summary: "Disable an extension for a PHP version or one project", Body: pitapi.PHPExtensionRequest{}, Response: pitapi.PHPExtension{}, Handler: h.phpExtensionDisable},
+
+		// projects
+		{Method: "GET", Path: "/v2/projects", OperationID: "listProjects", Tag: "projects",
+			Summary: "Project names", Response: pitapi.ProjectListResponse{}, Handler: h.projectList},
+		{Method: "POST", Path: "/v2/projects", OperationID: "createProject", Tag: "projects",
+			Summary: "Create a project", Body: pitapi.ProjectCreateRequest{}, Status: http.StatusCreated, Response: pitapi.ProjectConfig{}, Handler: h.projectCreate},
+		{Method: "GET", Path: "/v2/projects/{name}", OperationID: "getProject", Tag: "projects",
+			Summary: "Project config", Response: pitapi.ProjectConfig{}, Handler: h.projectGet},
+		{Method: "PATCH", Path: "/v2/projects/{name}", OperationID: "updateProject", Tag: "projects",
+			Summary: "Change project config and restart it", Body: pitapi.ProjectConfigPatch{}, Response: pitapi.ProjectConfig{}, Handler: h.projectUpdate},
+		{Method: "DELETE", Path: "/v2/projects/{name}", OperationID: "deleteProject", Tag: "projects",
+			Summary: "Stop and delete (or archive) a project", Response: pitapi.ProjectDeleteResponse{},
+			Query:   []queryParam{{Name: "archive", Type: "boolean", Description: "move to archive/ instead of deleting"}},
+			Handler: h.projectDelete},
+		{Method: "POST", Path: "/v2/projects/{name}/start", OperationID: "startProject", Tag: "projects",
+			Summary: "Start the project runtime", Response: pitapi.ProjectStateResponse{}, Handler: h.projectStart},
+		{Method: "POST", Path: "/v2/projects/{name}/stop", OperationID: "stopProject", Tag: "projects",
+			Summary: "Stop the project runtime", Response: pitapi.ProjectStateResponse{}, Handler: h.projectStop},
+		{Method: "POST", Path: "/v2/projects/{name}/restart", OperationID: "restartProject", Tag: "projects",
+			Summary: "Restart the project runtime", Response: pitapi.ProjectStateResponse{}, Handler: h.projectRestart},
+		{Method: "GET", Path: "/v2/projects/{name}/status", OperationID: "getProjectStatus", Tag: "projects",
+			Summary: "Status of the project services", Response: services{}, Handler: h.projectStatus},
+		{Method: "PUT", Path: "/v2/projects/{name}/port", OperationID: "setProjectPort", Tag: "projects",
+			Summary: "Change the project port and restart it", Body: pitapi.PortRequest{}, Response: pitapi.ProjectConfig{}, Handler: h.projectSetPort},
+
+		// tools
+		{Method: "POST", Path: "/v2/tools/sync", OperationID: "syncTools", Tag: "tools",
+			Summary: "Rescan tools/, rewrite vhosts and hosts, reload nginx", Response: pitapi.StatusResponse{}, Handler: h.toolsSync},
+	}
+}
+
+// ================================
+// ENGINE
+// ================================
+
+func (h *v2Handler) status(w http.ResponseWriter, r *http.Request) {
+	writeJSONStatus(w, http.StatusOK, h.engine.FullStatus())
+}
+
+func (h *v2Handler) services(w http.ResponseWriter, r *http.Request) {
+	writeJSONStatus(w, http.StatusOK, h.engine.ServiceStatuses())
+}
+
+func (h *v2Handler) engineStart(w http.ResponseWriter, r *http.Request) {
+	if err := h.engine.StartAll(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.StatusResponse{Status: "started"})
+}
+
+func (h *v2Handler) engineStop(w http.ResponseWriter, r *http.Request) {
+	if err := h.engine.StopAll(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.StatusResponse{Status: "stopped"})
+}
+
+func (h *v2Handler) engineReload(w http.ResponseWriter, r *http.Request) {
+	if err := h.engine.ReloadConfig(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.StatusResponse{Status: "reloaded"})
+}
+
+// ================================
+// PHP
+// ================================
+
+func (h *v2Handler) phpVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.engine.ListPHPVersions()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if versions == nil {
+		versions = []string{}
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.PHPVersionsResponse{Versions: versions})
+}
+
+func (h *v2Handler) phpCurrent(w http.ResponseWriter, r *http.Request) {
+	writeJSONStatus(w, http.StatusOK, pitapi.PHPVersionResponse{Version: h.engine.CurrentPHPVersion()})
+}
+
+func (h *v2Handler) phpUse(w http.ResponseWriter, r *http.Request) {
+	var req pitapi.PHPVersionRequest
+	if err := decodeBody(r, &req, false); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := required("version", req.Version); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.engine.ForceKillAllProjectRuntimes()
+
+	if err := h.engine.SetPHPVersion(req.Version); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.PHPVersionResponse{Version: req.Version})
+}
+
+func (h *v2Handler) phpExtensions(w http.ResponseWriter, r *http.Request) {
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		version = h.engine.CurrentPHPVersion()
+	}
+
+	list, err := core.ListPHPExtensions(h.engine.BasePath, version)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if list == nil {
+		list = []pitapi.PHPExtension{}
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.PHPExtensionsResponse{Version: version, Extensions: list})
+}
+
+func (h *v2Handler) phpExtensionEnable(w http.ResponseWriter, r *http.Request) {
+	h.phpExtensionSet(w, r, true)
+}
+
+func (h *v2Handler) phpExtensionDisable(w http.ResponseWriter, r *http.Request) {
+	h.phpExtensionSet(w, r, false)
+}
+
+// phpExtensionSet toggles {ext} for the version in the body (default:
+// current) or, with project set, in that project's pool only.
+func (h *v2Handler) phpExtensionSet(w http.ResponseWriter, r *http.Request, on bool) {
+	var req pitapi.PHPExtensionRequest
+	if err := decodeBody(r, &req, true); err != nil {
+		writeError(w, err)
+		return
+	}
+	ext := r.PathValue("ext")
+
+	version := req.Version
+	if req.Project != "" {
+		if err := core.ValidateProjectName(req.Project); err != nil {
+			writeError(w, err)
+			return
+		}
+		cfg, err := h.registry.SetProjectExtension(req.Project, ext, on)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		version = cfg.PHPVersion
+	} else {
+		if version == "" {
+			version = h.engine.CurrentPHPVersion()
+		}
+		set := core.DisablePHPExtension
+		if on {
+			set = core.EnablePHPExtension
+		}
+		if err := set(h.engine.BasePath, version, ext); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	info, err := core.PHPExtensionInfo(h.engine.BasePath, version, ext)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, info)
+}
+
+// ================================
+// PROJECTS
+// ================================
+
+func (h *v2Handler) projectList(w http.ResponseWriter, r *http.Request) {
+	names, err := h.registry.List()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.ProjectListResponse{Projects: names})
+}
+
+func (h *v2Handler) projectCreate(w http.ResponseWriter, r *http.Request) {
+	var req pitapi.ProjectCreateRequest
+	if err := decodeBody(r, &req, false); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := required("name", req.Name); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := h.registry.Create(req.Name); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	cfg, err := h.registry.ReadConfig(req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/v2/projects/"+req.Name)
+	writeJSONStatus(w, http.StatusCreated, cfg)
+}
+
+func (h *v2Handler) projectGet(w http.ResponseWriter, r *http.Request) {
+	name, err := projectName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	cfg, err := h.registry.ReadConfig(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, cfg)
+}
+
+func (h *v2Handler) projectUpdate(w http.ResponseWriter, r *http.Request) {
+	name, err := projectName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var patch core.ProjectConfigPatch
+	if err := decodeBody(r, &patch, false); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	cfg, err := h.registry.Update(name, patch)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, cfg)
+}
+
+func (h *v2Handler) projectDelete(w http.ResponseWriter, r *http.Request) {
+	name, err := projectName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	archive, err := queryBool(r, "archive")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	dst, err := h.registry.Delete(name, archive)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.ProjectDeleteResponse{Project: name, ArchivedTo: dst})
+}
+
+func (h *v2Handler) projectStart(w http.ResponseWriter, r *http.Request) {
+	h.projectAction(w, r, "running", func(p *core.ProjectEngine) error { return p.Start() })
+}
+
+func (h *v2Handler) projectStop(w http.ResponseWriter, r *http.Request) {
+	h.projectAction(w, r, "stopped", func(p *core.ProjectEngine) error { return p.Stop() })
+}
+
+func (h *v2Handler) projectRestart(w http.ResponseWriter, r *http.Request) {
+	h.projectAction(w, r, "running", func(p *core.ProjectEngine) error {
+		return h.registry.Restart(p.Name)
+	})
+}
+
+func (h *v2Handler) projectAction(w http.ResponseWriter, r *http.Request, state string, fn func(*core.ProjectEngine) error) {
+	name, err := projectName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	peng, err := h.registry.Load(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := fn(peng); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.ProjectStateResponse{Project: name, Status: state, Services: peng.Status()})
+}
+
+func (h *v2Handler) projectStatus(w http.ResponseWriter, r *http.Request) {
+	name, err := projectName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	peng, err := h.registry.Load(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, peng.Status())
+}
+
+func (h *v2Handler) projectSetPort(w http.ResponseWriter, r *http.Request) {
+	name, err := projectName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var req pitapi.PortRequest
+	if err := decodeBody(r, &req, false); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	cfg, err := h.registry.Update(name, core.ProjectConfigPatch{Port: &req.Port})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, cfg)
+}
+
+// ================================
+// TOOLS
+// ================================
+
+func (h *v2Handler) toolsSync(w http.ResponseWriter, r *http.Request) {
+	if err := h.engine.SyncTools(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, pitapi.StatusResponse{Status: "synced"})
+}
+
+// ================================
+// 404 / 405 AS JSON
+// ================================
+
+// withJSONFallback turns the mux's plain-text 404/405 into the error
+// envelope, keeping the Allow header.
+func withJSONFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &statusRecorder{header: http.Header{}}
+		mux.ServeHTTP(rec, r)
+
+		if rec.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			writeJSONStatus(w, http.StatusMethodNotAllowed, pitapi.ErrorEnvelope{
+				Error: pitapi.ErrorBody{Code: pitapi.CodeMethodNotAllowed, Message: r.Method + " not allowed on " + r.URL.Path},
+			})
+			return
+		}
+
+		writeJSONStatus(w, http.StatusNotFound, pitapi.ErrorEnvelope{
+			Error: pitapi.ErrorBody{Code: pitapi.CodeNotFound, Message: "no route for " + r.URL.Path},
+		})
+	})
+}
+
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header         { return s.header }
+func (s *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (s *statusRecorder) WriteHeader(code int)        { s.status = code }
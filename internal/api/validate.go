@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pit/internal/core"
+	"pit/pkg/pitapi"
+)
+
+// ================================
+// V2 REQUEST VALIDATION
+// ================================
+
+const maxBodyBytes = 1 << 20
+
+// projectName returns the {name} path value, validated.
+func projectName(r *http.Request) (string, error) {
+	name := r.PathValue("name")
+	if err := core.ValidateProjectName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// decodeBody decodes a JSON body into dst, rejecting unknown fields,
+// trailing data and oversized payloads. An empty body is allowed when
+// optional is true.
+func decodeBody(r *http.Request, dst any, optional bool) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			if optional {
+				return nil
+			}
+			return badRequest(pitapi.CodeInvalidRequest, "request body required")
+		}
+		return badRequest(pitapi.CodeInvalidRequest, "invalid json: "+err.Error())
+	}
+	if dec.More() {
+		return badRequest(pitapi.CodeInvalidRequest, "invalid json: trailing data")
+	}
+	return nil
+}
+
+// required rejects empty string fields.
+func required(field, value string) error {
+	if value == "" {
+		return badRequest(pitapi.CodeInvalidRequest, fmt.Sprintf("%s is required", field))
+	}
+	return nil
+}
+
+// queryBool parses an optional boolean query parameter.
+func queryBool(r *http.Request, key string) (bool, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, badRequest(pitapi.CodeInvalidRequest, fmt.Sprintf("%s must be a boolean", key))
+	}
+	return b, nil
+}
@@ -0,0 +1,336 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------
+// LOCAL CERTIFICATE AUTHORITY (certs/)
+// -----------------------------------------------------------
+//
+// `pit setup` creates a root CA once and installs it into the system
+// trust store. Every vhost (www site, project, tool) then gets a leaf
+// certificate signed by it, re-issued when its names change or it is
+// about to expire.
+//
+//	certs/ca.pem, certs/ca-key.pem
+//	certs/sites/<first-hostname>.pem / .key
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 397 * 24 * time.Hour // browsers reject longer leaf lifetimes
+
+	// RenewBefore is how close to expiry a leaf gets re-issued.
+	RenewBefore = 30 * 24 * time.Hour
+
+	caCommonName = "pit local development CA"
+)
+
+var ErrNoCA = errors.New("local CA not found (run: pit setup)")
+
+type Authority struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// Pair is a certificate / key file couple as nginx wants it.
+type Pair struct {
+	Cert string
+	Key  string
+}
+
+func New(base string) *Authority {
+	return &Authority{Dir: filepath.Join(base, "certs")}
+}
+
+func (a *Authority) CAPath() string    { return filepath.Join(a.Dir, "ca.pem") }
+func (a *Authority) caKeyPath() string { return filepath.Join(a.Dir, "ca-key.pem") }
+func (a *Authority) sitesDir() string  { return filepath.Join(a.Dir, "sites") }
+
+// Exists reports whether the CA has been created. HTTPS is only
+// generated once it has.
+func (a *Authority) Exists() bool {
+	_, err1 := os.Stat(a.CAPath())
+	_, err2 := os.Stat(a.caKeyPath())
+	return err1 == nil && err2 == nil
+}
+
+// EnsureCA creates the root CA unless it already exists. created tells
+// the caller whether it has to be (re)installed into the trust store.
+func (a *Authority) EnsureCA() (created bool, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Exists() {
+		if _, _, err := a.loadCA(); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	if err := os.MkdirAll(a.Dir, 0o755); err != nil {
+		return false, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return false, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return false, err
+	}
+
+	host, _ := os.Hostname()
+	now := time.Now()
+	tpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:         caCommonName,
+			Organization:       []string{"pit"},
+			OrganizationalUnit: []string{host},
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		return false, err
+	}
+	if err := writePair(a.CAPath(), a.caKeyPath(), der, key); err != nil {
+		return false, err
+	}
+
+	// leaves signed by a previous CA are useless now
+	_ = os.RemoveAll(a.sitesDir())
+	return true, nil
+}
+
+// Issue returns a certificate for names (the first one names the
+// files). An existing certificate is reused while it covers exactly
+// these names, was signed by the current CA and is not about to
+// expire.
+func (a *Authority) Issue(names []string) (Pair, error) {
+	if len(names) == 0 {
+		return Pair{}, fmt.Errorf("no hostnames to certify")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	caCert, caKey, err := a.loadCA()
+	if err != nil {
+		return Pair{}, err
+	}
+
+	base := filepath.Join(a.sitesDir(), fileName(names[0]))
+	pair := Pair{Cert: base + ".pem", Key: base + ".key"}
+
+	if leaf, err := readCert(pair.Cert); err == nil && a.fresh(leaf, caCert, names) {
+		if _, err := os.Stat(pair.Key); err == nil {
+			return pair, nil
+		}
+	}
+
+	if err := os.MkdirAll(a.sitesDir(), 0o755); err != nil {
+		return Pair{}, err
+	}
+	if err := issueLeaf(pair, names, caCert, caKey); err != nil {
+		return Pair{}, err
+	}
+	fmt.Printf("[Certs] Issued certificate for %s\n", strings.Join(names, ", "))
+	return pair, nil
+}
+
+// RenewExpiring re-issues every leaf that expires within RenewBefore
+// (or no longer chains to the CA), keeping its names and file paths so
+// existing vhosts pick it up on reload. It returns how many were renewed.
+func (a *Authority) RenewExpiring() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	caCert, caKey, err := a.loadCA()
+	if err != nil {
+		return 0, err
+	}
+
+	files, _ := filepath.Glob(filepath.Join(a.sitesDir(), "*.pem"))
+	renewed := 0
+	for _, f := range files {
+		leaf, err := readCert(f)
+		if err != nil || a.fresh(leaf, caCert, leaf.DNSNames) {
+			continue
+		}
+		pair := Pair{Cert: f, Key: strings.TrimSuffix(f, ".pem") + ".key"}
+		if err := issueLeaf(pair, leaf.DNSNames, caCert, caKey); err != nil {
+			return renewed, err
+		}
+		fmt.Printf("[Certs] Renewed certificate for %s\n", strings.Join(leaf.DNSNames, ", "))
+		renewed++
+	}
+	return renewed, nil
+}
+
+// Fingerprint is the SHA-256 of the CA certificate, for `pit setup`
+// output and manual browser imports.
+func (a *Authority) Fingerprint() (string, error) {
+	cert, err := readCert(a.CAPath())
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return strings.ToUpper(hex.EncodeToString(sum[:])), nil
+}
+
+// -----------------------------------------------------------
+// INTERNALS
+// -----------------------------------------------------------
+
+func (a *Authority) loadCA() (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	cert, err := readCert(a.CAPath())
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNoCA
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(a.caKeyPath())
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNoCA
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("corrupt %s", a.caKeyPath())
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("corrupt %s: %w", a.caKeyPath(), err)
+	}
+	return cert, key, nil
+}
+
+func (a *Authority) fresh(leaf, ca *x509.Certificate, names []string) bool {
+	if time.Until(leaf.NotAfter) < RenewBefore {
+		return false
+	}
+	if !bytes.Equal(leaf.RawIssuer, ca.RawSubject) || leaf.CheckSignatureFrom(ca) != nil {
+		return false
+	}
+	return sameNames(leaf.DNSNames, names)
+}
+
+func issueLeaf(pair Pair, names []string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   names[0],
+			Organization: []string{"pit"},
+		},
+		DNSNames:    names,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(leafValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	return writePair(pair.Cert, pair.Key, der, key)
+}
+
+// writePair writes the key (0600) before the certificate, both through
+// a temp file so nginx never reads half a file.
+func writePair(certPath, keyPath string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return writeAtomic(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+func writeAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("corrupt %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]string{}, a...)
+	y := append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fileName keeps wildcard names usable as file names.
+func fileName(host string) string {
+	return strings.ReplaceAll(host, "*", "_wildcard")
+}
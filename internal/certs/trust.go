@@ -0,0 +1,121 @@
+package certs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// -----------------------------------------------------------
+// SYSTEM TRUST STORE
+// -----------------------------------------------------------
+
+// trustStore is one distro family's anchor directory + refresh command.
+type trustStore struct {
+	Dir     string
+	File    string
+	Refresh []string
+}
+
+var trustStores = []trustStore{
+	// Debian / Ubuntu
+	{"/usr/local/share/ca-certificates", "pit-local-ca.crt", []string{"update-ca-certificates"}},
+	// Fedora / RHEL
+	{"/etc/pki/ca-trust/source/anchors", "pit-local-ca.pem", []string{"update-ca-trust", "extract"}},
+	// Arch
+	{"/etc/ca-certificates/trust-source/anchors", "pit-local-ca.crt", []string{"trust", "extract-compat"}},
+}
+
+const nssNickname = "pit local development CA"
+
+// InstallTrust copies the CA into the system trust store (sudo, like
+// the setcap step of `pit setup`) and, when certutil is available, into
+// the user's NSS database used by Chrome/Chromium.
+func (a *Authority) InstallTrust() error {
+	if !a.Exists() {
+		return ErrNoCA
+	}
+
+	store, ok := detectTrustStore()
+	if !ok {
+		return fmt.Errorf("no supported system trust store found; import %s manually", a.CAPath())
+	}
+
+	dst := filepath.Join(store.Dir, store.File)
+	fmt.Println("[Certs] Installing local CA into", dst)
+
+	if err := sudo("install", "-m", "0644", a.CAPath(), dst); err != nil {
+		return fmt.Errorf("copying CA to trust store: %w", err)
+	}
+	if err := sudo(store.Refresh...); err != nil {
+		return fmt.Errorf("refreshing trust store: %w", err)
+	}
+
+	a.installNSS()
+	return nil
+}
+
+// Installed reports whether the current CA is already in the system
+// trust store.
+func (a *Authority) Installed() bool {
+	store, ok := detectTrustStore()
+	if !ok {
+		return false
+	}
+	want, err := os.ReadFile(a.CAPath())
+	if err != nil {
+		return false
+	}
+	got, err := os.ReadFile(filepath.Join(store.Dir, store.File))
+	return err == nil && bytes.Equal(got, want)
+}
+
+func detectTrustStore() (trustStore, bool) {
+	for _, s := range trustStores {
+		if st, err := os.Stat(s.Dir); err != nil || !st.IsDir() {
+			continue
+		}
+		if _, err := exec.LookPath(s.Refresh[0]); err != nil {
+			continue
+		}
+		return s, true
+	}
+	return trustStore{}, false
+}
+
+// installNSS is best-effort: browsers with their own store keep
+// working once the CA is imported by hand.
+func (a *Authority) installNSS() {
+	certutil, err := exec.LookPath("certutil")
+	if err != nil {
+		return
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	db := filepath.Join(home, ".pki", "nssdb")
+	if _, err := os.Stat(db); err != nil {
+		return
+	}
+
+	// replace an older pit CA with the same nickname
+	_ = exec.Command(certutil, "-D", "-d", "sql:"+db, "-n", nssNickname).Run()
+
+	cmd := exec.Command(certutil, "-A", "-d", "sql:"+db, "-t", "C,,", "-n", nssNickname, "-i", a.CAPath())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		fmt.Printf("[Certs] NSS import failed: %v %s\n", err, out)
+		return
+	}
+	fmt.Println("[Certs] CA added to", db)
+}
+
+func sudo(args ...string) error {
+	cmd := exec.Command("sudo", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
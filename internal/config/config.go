@@ -7,11 +7,31 @@ import (
 
 type EngineConfig struct {
 	PHPVersion string `json:"php_version"`
+
+	// seconds to wait after the graceful stop signal before SIGKILL
+	StopTimeout int `json:"stop_timeout,omitempty"`
+
+	API APIConfig `json:"api"`
+}
+
+// APIConfig controls the optional TCP listener. The control socket
+// (runtime/pit.sock) is always on and protected by file permissions;
+// TCP is off by default and, when enabled, loopback-only and
+// token-protected.
+type APIConfig struct {
+	TCP    bool   `json:"tcp"`
+	Listen string `json:"listen,omitempty"`
+	Token  string `json:"token,omitempty"`
 }
 
+const DefaultAPIListen = "127.0.0.1:7070"
+
 func DefaultConfig() EngineConfig {
 	return EngineConfig{
 		PHPVersion: "83",
+		API: APIConfig{
+			Listen: DefaultAPIListen,
+		},
 	}
 }
 
@@ -29,6 +49,9 @@ func Load(path string) EngineConfig {
 	if cfg.PHPVersion == "" {
 		cfg.PHPVersion = DefaultConfig().PHPVersion
 	}
+	if cfg.API.Listen == "" {
+		cfg.API.Listen = DefaultAPIListen
+	}
 
 	return cfg
 }
@@ -43,7 +66,8 @@ func Save(path string, cfg EngineConfig) error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0o644)
+	// may contain the API token
+	return os.WriteFile(path, data, 0o600)
 }
 
 func dir(path string) string {
@@ -0,0 +1,366 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	util "pit/internal/utils"
+)
+
+// -----------------------------------------------------------
+// PORTABLE BUILDS (php/<ver>, nginx/)
+// -----------------------------------------------------------
+//
+// A build comes as a directory or a .tar(.gz) archive, optionally
+// wrapped in a single top-level directory. It must ship a SHA256SUMS
+// manifest (sha256sum format) covering at least its binaries. Install
+// unpacks into runtime/_install, verifies, links shared libraries into
+// libs/ (what LD_LIBRARY_PATH points at), runs the binary with -v, writes
+// pit's default configs and only then moves the build in place.
+
+const buildManifest = "SHA256SUMS"
+
+type InstallOptions struct {
+	Name     string // php/<Name>; default major+minor from php-fpm -v ("83")
+	NoVerify bool   // skip the SHA256SUMS check
+}
+
+var (
+	buildNameRe    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	phpVersionRe   = regexp.MustCompile(`PHP (\d+)\.(\d+)\.\d+`)
+	nginxVersionRe = regexp.MustCompile(`nginx/(\d+\.\d+\.\d+)`)
+)
+
+// ---------- php ----------
+
+// InstallPHP installs a portable PHP build as php/<ver> and returns
+// <ver>.
+func (e *Engine) InstallPHP(src string, opts InstallOptions) (string, error) {
+	stage, err := stageBuild(e.BasePath, src)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stage)
+
+	fpm := filepath.Join("sbin", "php-fpm")
+	if err := verifyBuild(stage, opts.NoVerify, fpm); err != nil {
+		return "", err
+	}
+	linkLibs(stage)
+
+	out, err := probeBuild(stage, fpm)
+	m := phpVersionRe.FindStringSubmatch(out)
+	if err != nil || m == nil {
+		return "", fmt.Errorf("%w: php-fpm -v failed: %s", ErrInvalidBuild, firstLine(out, err))
+	}
+	fmt.Println("[Install]", firstLine(out, nil))
+
+	name := opts.Name
+	if name == "" {
+		name = m[1] + m[2]
+	}
+	if !buildNameRe.MatchString(name) {
+		return "", fmt.Errorf("%w: version name %q", ErrInvalidBuild, name)
+	}
+
+	dst := filepath.Join(e.BasePath, "php", name)
+	if _, err := os.Stat(dst); err == nil {
+		return "", fmt.Errorf("%w: php/%s (pit php remove %s first)", ErrBuildExists, name, name)
+	}
+
+	if err := writePHPDefaults(stage, dst); err != nil {
+		return "", err
+	}
+
+	_ = os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err := os.Rename(stage, dst); err != nil {
+		return "", err
+	}
+	util.PreparePHPDirs(dst)
+	return name, nil
+}
+
+// RemovePHP deletes php/<ver> unless the engine, a project or a
+// running FPM master still uses it.
+func (e *Engine) RemovePHP(ver string) error {
+	dir := filepath.Join(e.BasePath, "php", ver)
+	if !buildNameRe.MatchString(ver) {
+		return fmt.Errorf("%w: %s", ErrPHPVersionNotFound, ver)
+	}
+	if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+		return fmt.Errorf("%w: %s", ErrPHPVersionNotFound, ver)
+	}
+
+	if ver == e.Config.PHPVersion {
+		return fmt.Errorf("%w: %s is the global PHP version (pit php use <other> first)", ErrBuildInUse, ver)
+	}
+
+	var users []string
+	names, _ := NewProjectRegistry(e.BasePath).List()
+	for _, p := range names {
+		if cfg, err := LoadProjectConfig(e.BasePath, p); err == nil && cfg.PHPVersion == ver {
+			users = append(users, p)
+		}
+	}
+	if len(users) > 0 {
+		return fmt.Errorf("%w: php %s is used by %s", ErrBuildInUse, ver, strings.Join(users, ", "))
+	}
+
+	if pid := util.GetPID(filepath.Join(dir, "logs", "php-fpm.pid")); util.IsAlive(pid) {
+		return fmt.Errorf("%w: php-fpm %s is running (pid %d)", ErrBuildInUse, ver, pid)
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// writePHPDefaults writes etc/php-fpm.conf and etc/php.ini into the
+// staged build unless it ships its own. Paths point at dst, where the
+// build ends up.
+func writePHPDefaults(stage, dst string) error {
+	etc := filepath.Join(stage, "etc")
+	for _, d := range []string{etc, filepath.Join(etc, "php-fpm.d"), filepath.Join(etc, "conf.d")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return err
+		}
+	}
+
+	fpmConf := filepath.Join(etc, "php-fpm.conf")
+	if _, err := os.Stat(fpmConf); os.IsNotExist(err) {
+		conf := fmt.Sprintf(`; generated by pit php install
+[global]
+pid = %s
+error_log = %s
+daemonize = no
+include = %s
+
+; www/ sites (127.0.0.1:9099); projects get their own pools in php-fpm.d
+[www]
+listen = 127.0.0.1:9099
+pm = dynamic
+pm.max_children = 5
+pm.start_servers = 2
+pm.min_spare_servers = 1
+pm.max_spare_servers = 3
+`, filepath.Join(dst, "logs", "php-fpm.pid"),
+			filepath.Join(dst, "logs", "php-fpm.log"),
+			filepath.Join(dst, "etc", "php-fpm.d", "*.conf"))
+		if err := os.WriteFile(fpmConf, []byte(conf), 0o644); err != nil {
+			return err
+		}
+	}
+
+	ini := filepath.Join(etc, "php.ini")
+	if _, err := os.Stat(ini); !os.IsNotExist(err) {
+		return err
+	}
+
+	// start from the build's php.ini-development when it has one
+	var content []byte
+	candidates, _ := filepath.Glob(filepath.Join(stage, "*", "php.ini-development"))
+	candidates = append([]string{filepath.Join(stage, "php.ini-development")}, candidates...)
+	for _, c := range candidates {
+		if data, err := os.ReadFile(c); err == nil {
+			content = append(data, '\n')
+			break
+		}
+	}
+
+	// the compiled-in extension_dir is the build machine's prefix
+	content = append(content, "; --- pit ---\n"...)
+	if dirs, _ := filepath.Glob(filepath.Join(stage, "lib", "php", "extensions", "*")); len(dirs) > 0 {
+		rel, _ := filepath.Rel(stage, dirs[0])
+		content = append(content, fmt.Sprintf("extension_dir = \"%s\"\n", filepath.Join(dst, rel))...)
+	}
+	content = append(content, fmt.Sprintf("error_log = %s\n", filepath.Join(dst, "logs", "php-error.log"))...)
+	return os.WriteFile(ini, content, 0o644)
+}
+
+// ---------- nginx ----------
+
+// InstallNginx installs a portable nginx build as nginx/ and returns
+// its version.
+func (e *Engine) InstallNginx(src string, opts InstallOptions) (string, error) {
+	dst := filepath.Join(e.BasePath, "nginx")
+	if _, err := os.Stat(filepath.Join(dst, "sbin", "nginx")); err == nil {
+		return "", fmt.Errorf("%w: nginx/ (pit nginx remove first)", ErrBuildExists)
+	}
+	if entries, err := os.ReadDir(dst); err == nil && len(entries) > 0 {
+		return "", fmt.Errorf("%w: nginx/ is not empty, move it away first", ErrBuildExists)
+	}
+
+	stage, err := stageBuild(e.BasePath, src)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stage)
+
+	bin := filepath.Join("sbin", "nginx")
+	if err := verifyBuild(stage, opts.NoVerify, bin); err != nil {
+		return "", err
+	}
+	for _, f := range []string{"mime.types", "fastcgi.conf"} {
+		if _, err := os.Stat(filepath.Join(stage, "conf", f)); err != nil {
+			return "", fmt.Errorf("%w: conf/%s missing", ErrInvalidBuild, f)
+		}
+	}
+	linkLibs(stage)
+
+	out, err := probeBuild(stage, bin)
+	m := nginxVersionRe.FindStringSubmatch(out)
+	if err != nil || m == nil {
+		return "", fmt.Errorf("%w: nginx -v failed: %s", ErrInvalidBuild, firstLine(out, err))
+	}
+
+	_ = os.Remove(dst) // empty placeholder
+	if err := os.Rename(stage, dst); err != nil {
+		return "", err
+	}
+	util.PrepareNginxDirs(dst)
+	return m[1], nil
+}
+
+// RemoveNginx deletes the nginx/ build when nothing runs on it.
+func (e *Engine) RemoveNginx() error {
+	dir := filepath.Join(e.BasePath, "nginx")
+	if _, err := os.Stat(filepath.Join(dir, "sbin", "nginx")); err != nil {
+		return fmt.Errorf("%w: no nginx build installed", ErrInvalidBuild)
+	}
+
+	if pid, ok := e.Running(); ok {
+		return fmt.Errorf("%w: pit is running (pid %d), stop it first", ErrBuildInUse, pid)
+	}
+	pidFiles := []string{filepath.Join(dir, "logs", "nginx.pid")}
+	names, _ := NewProjectRegistry(e.BasePath).List()
+	for _, p := range names {
+		pidFiles = append(pidFiles, filepath.Join(e.BasePath, "runtime", p, "run", "nginx.pid"))
+	}
+	for _, f := range pidFiles {
+		if pid := util.GetPID(f); util.IsAlive(pid) {
+			return fmt.Errorf("%w: nginx is running (pid %d)", ErrBuildInUse, pid)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// ---------- shared steps ----------
+
+// stageBuild copies or unpacks src into a fresh runtime/_install/ dir.
+func stageBuild(base, src string) (string, error) {
+	st, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+
+	root := filepath.Join(base, "runtime", "_install")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return "", err
+	}
+	stage, err := os.MkdirTemp(root, "build-")
+	if err != nil {
+		return "", err
+	}
+	_ = os.Chmod(stage, 0o755) // becomes php/<ver> or nginx/
+
+	switch {
+	case st.IsDir():
+		err = util.CopyTree(src, stage)
+	case strings.HasSuffix(src, ".tar.gz"), strings.HasSuffix(src, ".tgz"), strings.HasSuffix(src, ".tar"):
+		if err = util.ExtractTar(src, stage); err == nil {
+			err = util.StripSingleDir(stage)
+		}
+	default:
+		err = fmt.Errorf("%w: %s is not a directory or .tar.gz archive", ErrInvalidBuild, src)
+	}
+	if err != nil {
+		os.RemoveAll(stage)
+		return "", err
+	}
+	return stage, nil
+}
+
+// verifyBuild checks SHA256SUMS and that it covers the required files.
+func verifyBuild(stage string, skip bool, required ...string) error {
+	for _, f := range required {
+		if _, err := os.Stat(filepath.Join(stage, f)); err != nil {
+			return fmt.Errorf("%w: %s missing", ErrInvalidBuild, f)
+		}
+	}
+	if skip {
+		fmt.Println("[Install] Checksum verification skipped")
+		return nil
+	}
+
+	verified, err := util.VerifyChecksums(stage, filepath.Join(stage, buildManifest))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: no %s manifest (use --no-verify to install anyway)", ErrInvalidBuild, buildManifest)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBuild, err)
+	}
+
+	covered := map[string]bool{}
+	for _, f := range verified {
+		covered[f] = true
+	}
+	for _, f := range required {
+		if !covered[f] {
+			return fmt.Errorf("%w: %s is not covered by %s", ErrInvalidBuild, f, buildManifest)
+		}
+	}
+	fmt.Printf("[Install] %d files verified against %s\n", len(verified), buildManifest)
+	return nil
+}
+
+// linkLibs makes libs/ hold every shared library the build ships in
+// lib/, lib64/ or lib/<triplet>/, as relative symlinks.
+func linkLibs(stage string) {
+	libs := filepath.Join(stage, "libs")
+	_ = os.MkdirAll(libs, 0o755)
+
+	dirs := []string{filepath.Join(stage, "lib"), filepath.Join(stage, "lib64")}
+	triplets, _ := filepath.Glob(filepath.Join(stage, "lib", "*-linux-gnu"))
+	dirs = append(dirs, triplets...)
+
+	for _, dir := range dirs {
+		entries, _ := os.ReadDir(dir)
+		for _, ent := range entries {
+			name := ent.Name()
+			if ent.IsDir() || !(strings.HasSuffix(name, ".so") || strings.Contains(name, ".so.")) {
+				continue
+			}
+			link := filepath.Join(libs, name)
+			if _, err := os.Lstat(link); err == nil {
+				continue // the build's own libs/ wins
+			}
+			rel, _ := filepath.Rel(libs, filepath.Join(dir, name))
+			_ = os.Symlink(rel, link)
+		}
+	}
+}
+
+// probeBuild runs "<bin> -v" with the build's libs and returns its
+// output (nginx prints the version on stderr).
+func probeBuild(stage, bin string) (string, error) {
+	cmd := exec.Command(filepath.Join(stage, bin), "-v")
+	cmd.Env = append(os.Environ(),
+		"LD_LIBRARY_PATH="+filepath.Join(stage, "libs")+":"+os.Getenv("LD_LIBRARY_PATH"),
+	)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func firstLine(out string, err error) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	if line == "" && err != nil {
+		return err.Error()
+	}
+	return line
+}
@@ -0,0 +1,286 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pit/internal/cron"
+	"pit/internal/events"
+	"pit/internal/services"
+	util "pit/internal/utils"
+	"pit/pkg/pitapi"
+)
+
+// -----------------------------------------------------------
+// CRON SCHEDULER (per-project scheduled commands)
+// -----------------------------------------------------------
+//
+// The engine checks every minute which jobs of the running projects
+// are due and runs them with the project's PHP on PATH and its env.
+// Output is appended to runtime/<name>/logs/cron.log; last run, exit
+// code and the pid of a running job are kept in runtime/<name>/cron.json
+// so status works from any pit process.
+
+type CronJob = pitapi.CronJob
+
+// CronState is the bookkeeping of one job.
+type CronState struct {
+	LastRun  time.Time `json:"last_run"`
+	Duration float64   `json:"duration"` // seconds
+	ExitCode int       `json:"exit_code"`
+	PID      int       `json:"pid,omitempty"` // set while running
+}
+
+func validateCron(jobs []CronJob) error {
+	seen := map[string]bool{}
+	for _, j := range jobs {
+		switch {
+		case !processNameRe.MatchString(j.Name):
+			return fmt.Errorf("%w: cron job name %q (use a-z, 0-9, - and _)", ErrInvalidConfig, j.Name)
+		case seen[j.Name]:
+			return fmt.Errorf("%w: duplicate cron job %q", ErrInvalidConfig, j.Name)
+		case strings.TrimSpace(j.Command) == "":
+			return fmt.Errorf("%w: cron job %q has no command", ErrInvalidConfig, j.Name)
+		}
+		seen[j.Name] = true
+
+		if _, err := cron.Parse(j.Schedule); err != nil {
+			return fmt.Errorf("%w: cron job %q: %v", ErrInvalidConfig, j.Name, err)
+		}
+		if dir := filepath.Clean(j.Dir); filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			return fmt.Errorf("%w: cron job %q dir must be inside the project", ErrInvalidConfig, j.Name)
+		}
+	}
+	return nil
+}
+
+// ---------- scheduler loop ----------
+
+// watchCron runs due jobs at the top of every minute until the engine
+// stops. A job still running from its previous slot is skipped.
+func (e *Engine) watchCron() {
+	running := &sync.Map{} // "<project>/<job>" → struct{}
+
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+
+		select {
+		case <-e.done:
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		e.runDueCron(next, running)
+	}
+}
+
+func (e *Engine) runDueCron(at time.Time, running *sync.Map) {
+	reg := NewProjectRegistry(e.BasePath)
+	projects, err := reg.List()
+	if err != nil {
+		return
+	}
+
+	for _, name := range projects {
+		cfg, err := LoadProjectConfig(e.BasePath, name)
+		if err != nil || len(cfg.Cron) == 0 {
+			continue
+		}
+
+		var due []CronJob
+		for _, j := range cfg.Cron {
+			s, err := cron.Parse(j.Schedule)
+			if err == nil && s.Match(at) {
+				due = append(due, j)
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+
+		// only projects that are up get their jobs run
+		peng, err := reg.Load(name)
+		if err != nil || !peng.Running() {
+			continue
+		}
+
+		for _, j := range due {
+			key := name + "/" + j.Name
+			if _, busy := running.LoadOrStore(key, struct{}{}); busy {
+				fmt.Printf("[Cron] %s: %s still running, skipped\n", name, j.Name)
+				continue
+			}
+			go func(cfg *ProjectConfig, j CronJob) {
+				defer running.Delete(key)
+				runCronJob(e.BasePath, cfg, j)
+			}(cfg, j)
+		}
+	}
+}
+
+// ---------- running a job ----------
+
+var cronStateMu sync.Mutex
+
+func runCronJob(base string, cfg *ProjectConfig, j CronJob) {
+	logPath := filepath.Join(base, "runtime", cfg.Name, "logs", "cron.log")
+	_ = os.MkdirAll(filepath.Dir(logPath), 0o755)
+
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		fmt.Println("[Cron]", cfg.Name, j.Name+":", err)
+		return
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command("sh", "-c", j.Command)
+	cmd.Dir = filepath.Join(base, "projects", cfg.Name, j.Dir)
+	cmd.Env = cronEnviron(base, cfg)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	started := time.Now()
+	fmt.Fprintf(logFile, "[%s] %s: %s\n", started.Format(time.DateTime), j.Name, j.Command)
+
+	code := -1
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(logFile, "[%s] %s: %v\n", time.Now().Format(time.DateTime), j.Name, err)
+	} else {
+		updateCronState(base, cfg.Name, j.Name, func(st *CronState) {
+			st.PID = cmd.Process.Pid
+		})
+
+		err = cmd.Wait()
+		code = 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+	}
+
+	took := time.Since(started)
+	fmt.Fprintf(logFile, "[%s] %s: exit %d after %s\n", time.Now().Format(time.DateTime), j.Name, code, took.Round(time.Millisecond))
+
+	updateCronState(base, cfg.Name, j.Name, func(st *CronState) {
+		st.LastRun = started
+		st.Duration = took.Seconds()
+		st.ExitCode = code
+		st.PID = 0
+	})
+
+	events.Publish(events.Event{
+		Type:    events.CronFinished,
+		Project: cfg.Name,
+		Data: map[string]any{
+			"job":       j.Name,
+			"exit_code": code,
+			"duration":  took.Seconds(),
+		},
+	})
+}
+
+// cronEnviron mirrors what sidecar processes get: project PHP first on
+// PATH (with its conf.d) plus the resolved project env.
+func cronEnviron(base string, cfg *ProjectConfig) []string {
+	env := os.Environ()
+	if path := phpBinPath(base, cfg.PHPVersion); len(path) > 0 {
+		env = append(env, "PATH="+path[0]+string(os.PathListSeparator)+os.Getenv("PATH"))
+	}
+
+	vars, _, err := cfg.ResolveEnv(base)
+	if err != nil {
+		fmt.Println("[Cron]", cfg.Name+":", err)
+	}
+	vars = mergeEnv(phpCLIEnv(base, cfg.PHPVersion), vars)
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+vars[k])
+	}
+	return env
+}
+
+// ---------- state ----------
+
+func cronStatePath(base, project string) string {
+	return filepath.Join(base, "runtime", project, "cron.json")
+}
+
+func readCronState(base, project string) map[string]CronState {
+	state := map[string]CronState{}
+	data, err := os.ReadFile(cronStatePath(base, project))
+	if err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func updateCronState(base, project, job string, fn func(*CronState)) {
+	cronStateMu.Lock()
+	defer cronStateMu.Unlock()
+
+	state := readCronState(base, project)
+	st := state[job]
+	fn(&st)
+	state[job] = st
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return
+	}
+	path := cronStatePath(base, project)
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	tmp := path + ".tmp"
+	if os.WriteFile(tmp, data, 0o644) == nil {
+		_ = os.Rename(tmp, path)
+	}
+}
+
+// cronStatus reports every configured job as a "cron:<job>" service
+// entry with its schedule, last/next run and last exit code.
+func cronStatus(base string, cfg *ProjectConfig) map[string]services.ServiceStatus {
+	if len(cfg.Cron) == 0 {
+		return nil
+	}
+
+	state := readCronState(base, cfg.Name)
+	now := time.Now()
+
+	out := map[string]services.ServiceStatus{}
+	for _, j := range cfg.Cron {
+		st := services.ServiceStatus{Schedule: j.Schedule}
+
+		if s, err := cron.Parse(j.Schedule); err == nil {
+			if next := s.Next(now); !next.IsZero() {
+				st.NextRun = &next
+			}
+		}
+
+		if cs, ok := state[j.Name]; ok {
+			if cs.PID > 0 && util.IsAlive(cs.PID) {
+				st.Running = true
+				st.PID = cs.PID
+			}
+			if !cs.LastRun.IsZero() {
+				last := cs.LastRun
+				code := cs.ExitCode
+				st.LastRun = &last
+				st.ExitCode = &code
+			}
+		}
+		out["cron:"+j.Name] = st
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pit/internal/services"
+	"pit/internal/tools"
+)
+
+// -----------------------------------------------------------
+// PROJECT DOMAINS
+// -----------------------------------------------------------
+
+var hostLabelRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// DefaultDomain is the hostname of a project without explicit domains.
+func DefaultDomain(project string) string {
+	return strings.ToLower(strings.ReplaceAll(project, "_", "-")) + ".test"
+}
+
+// Hostnames returns every name the project answers to: its domains
+// (or the default) followed by its aliases.
+func (cfg *ProjectConfig) Hostnames() []string {
+	hosts := cfg.Domains
+	if len(hosts) == 0 {
+		hosts = []string{DefaultDomain(cfg.Name)}
+	}
+	return append(append([]string{}, hosts...), cfg.Aliases...)
+}
+
+// ValidateHostname accepts lower-case DNS names with at least two
+// labels; wildcard allows a leading "*." (aliases only).
+func ValidateHostname(host string, wildcard bool) error {
+	h := host
+	if wildcard {
+		h = strings.TrimPrefix(h, "*.")
+	}
+	labels := strings.Split(h, ".")
+	if len(h) > 253 || len(labels) < 2 {
+		return fmt.Errorf("%w: %q", ErrInvalidDomain, host)
+	}
+	for _, l := range labels {
+		if !hostLabelRe.MatchString(l) {
+			return fmt.Errorf("%w: %q", ErrInvalidDomain, host)
+		}
+	}
+	return nil
+}
+
+// normalizeHosts lower-cases, trims, validates and de-duplicates.
+func normalizeHosts(in []string, wildcard bool) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, h := range in {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" || seen[h] {
+			continue
+		}
+		if err := ValidateHostname(h, wildcard); err != nil {
+			return nil, err
+		}
+		seen[h] = true
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// checkDomains fails if one of hosts already belongs to another
+// project, a www/ site or a tool.
+func (r *ProjectRegistry) checkDomains(name string, hosts []string) error {
+	owners := domainOwners(r.BasePath, name)
+	for _, h := range hosts {
+		if owner, ok := owners[h]; ok {
+			return fmt.Errorf("%w: %s is used by %s", ErrDomainConflict, h, owner)
+		}
+	}
+	return nil
+}
+
+// domainOwners maps every hostname pit routes to a description of its
+// owner, skipping project exclude.
+func domainOwners(base, exclude string) map[string]string {
+	owners := map[string]string{}
+
+	for _, d := range wwwDomains(base) {
+		owners[d] = "www site " + strings.TrimSuffix(d, ".test")
+	}
+
+	if ms, err := tools.Scan(base); err == nil {
+		for _, m := range ms {
+			if m.Domain != "" {
+				owners[m.Domain] = "tool " + m.Name
+			}
+		}
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(base, "projects"))
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == exclude {
+			continue
+		}
+		cfg, err := LoadProjectConfig(base, e.Name())
+		if err != nil {
+			continue
+		}
+		for _, h := range cfg.Hostnames() {
+			owners[h] = fmt.Sprintf("project %q", e.Name())
+		}
+	}
+	return owners
+}
+
+// wwwDomains lists the <dir>.test names of the www/ sites.
+func wwwDomains(base string) []string {
+	entries, _ := os.ReadDir(filepath.Join(base, "www"))
+
+	var domains []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			domains = append(domains, entry.Name()+".test")
+		}
+	}
+	return domains
+}
+
+// -----------------------------------------------------------
+// ROUTES FOR THE GLOBAL NGINX
+// -----------------------------------------------------------
+
+// projectRoutes builds the hostname → project port table the global
+// nginx proxies. Names already taken by a www/ site are skipped.
+func projectRoutes(base string) []services.ProjectRoute {
+	taken := map[string]bool{}
+	for _, d := range wwwDomains(base) {
+		taken[d] = true
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(base, "projects"))
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var routes []services.ProjectRoute
+	for _, name := range names {
+		cfg, err := LoadProjectConfig(base, name)
+		if err != nil || cfg.Port == 0 {
+			continue
+		}
+
+		var hosts []string
+		for _, h := range cfg.Hostnames() {
+			if taken[h] {
+				fmt.Printf("[Domains] %s: %s is already served by www/, skipping\n", name, h)
+				continue
+			}
+			taken[h] = true
+			hosts = append(hosts, h)
+		}
+		if len(hosts) > 0 {
+			routes = append(routes, services.ProjectRoute{Project: name, Hostnames: hosts, Port: cfg.Port})
+		}
+	}
+	return routes
+}
+
+// hostsDomains is everything that should resolve to 127.0.0.1: www
+// sites plus project domains (wildcards cannot go into /etc/hosts).
+func hostsDomains(base string) []string {
+	domains := wwwDomains(base)
+	for _, rt := range projectRoutes(base) {
+		for _, h := range rt.Hostnames {
+			if !strings.HasPrefix(h, "*.") {
+				domains = append(domains, h)
+			}
+		}
+	}
+	return domains
+}
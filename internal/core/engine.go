@@ -1,22 +1,38 @@
 package core
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"pit/internal/certs"
 	"pit/internal/config"
+	"pit/internal/events"
+	"pit/internal/procfs"
 	"pit/internal/services"
+	"pit/internal/tools"
 	util "pit/internal/utils"
+	"pit/pkg/pitapi"
 )
 
 type Engine struct {
 	BasePath string
 	Config   config.EngineConfig
 	Services []services.Service
+
+	pidLock   *PIDLock
+	startedAt time.Time
+
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 // Constructor utama engine
@@ -29,19 +45,53 @@ func NewEngine(base string) *Engine {
 	e := &Engine{
 		BasePath: base,
 		Config:   cfg,
+		done:     make(chan struct{}),
 	}
 
+	e.applyConfig()
+
 	e.ensureToolsPHPConfig()
 
+	nginx := services.NewNginxService(base, www)
+	nginx.Routes = func() []services.ProjectRoute { return projectRoutes(base) }
+	nginx.Certs = certs.New(base)
+
 	e.Services = []services.Service{
 		services.NewPHPService(base, cfg.PHPVersion),  // project PHP
 		services.NewToolsPHPService(base, e.PHPBin()), // 👈 TOOLS PHP
-		services.NewNginxService(base, www),
+		nginx,
 	}
 
 	return e
 }
 
+// apply runtime knobs from config
+func (e *Engine) applyConfig() {
+	if e.Config.StopTimeout > 0 {
+		util.StopTimeout = time.Duration(e.Config.StopTimeout) * time.Second
+	}
+}
+
+// APIToken returns the token protecting the TCP API, generating and
+// persisting one on first use.
+func (e *Engine) APIToken() (string, error) {
+	if e.Config.API.Token != "" {
+		return e.Config.API.Token, nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	e.Config.API.Token = hex.EncodeToString(buf)
+
+	if err := e.saveConfig(); err != nil {
+		return "", err
+	}
+	fmt.Println("[API] Generated TCP token in config/engine.json")
+	return e.Config.API.Token, nil
+}
+
 // Menyimpan config (php_version dll)
 func (e *Engine) saveConfig() error {
 	cfgPath := filepath.Join(e.BasePath, "config", "engine.json")
@@ -84,8 +134,8 @@ func (e *Engine) cleanupProjectRuntimes() {
 		phpSock := filepath.Join(phpDir, "php-fpm.sock")
 
 		// Kill by PID
-		killPidFile(phpPid)
-		killPidFile(nginxPid)
+		killPidFile(e.BasePath, phpPid, util.GracefulSignal("php-fpm"))
+		killPidFile(e.BasePath, nginxPid, util.GracefulSignal("nginx"))
 
 		// 🔥 IMPORTANT: remove stale socket
 		removeSocket(phpSock)
@@ -93,8 +143,7 @@ func (e *Engine) cleanupProjectRuntimes() {
 		// Extra safety: kill by port (jika config ada)
 		cfg, err := LoadProjectConfig(e.BasePath, project)
 		if err == nil {
-			killPort(cfg.Port)       // nginx
-			killPort(cfg.Port + 100) // php-fpm (tcp fallback)
+			killPort(e.BasePath, cfg.Port) // nginx
 		}
 	}
 }
@@ -104,62 +153,130 @@ func (e *Engine) cleanupProjectRuntimes() {
 func (e *Engine) StartAll() error {
 	fmt.Println("=== pit START ===")
 
-	pidFile := filepath.Join(e.BasePath, "runtime", "pit.pid")
-
-	if pid, err := ReadPID(pidFile); err == nil {
-		if util.IsAlive(pid) {
-			fmt.Println("pit already running.")
-			return nil
-		}
-		fmt.Println("Found stale pit PID, cleaning up ...")
-		KillPID(pidFile)
-	}
+	pidFile := e.PIDFile()
 
-	// tulis PID engine utama
-	if err := os.MkdirAll(filepath.Dir(pidFile), 0o755); err != nil {
-		return fmt.Errorf("failed to create runtime dir: %w", err)
+	// tulis PID engine utama (atomic, under flock)
+	lock, err := AcquirePIDLock(pidFile)
+	if errors.Is(err, ErrAlreadyRunning) {
+		pid, _ := ReadPID(pidFile)
+		return fmt.Errorf("%w (pid %d)", ErrAlreadyRunning, pid)
 	}
-	mainPID := os.Getpid()
-	if err := WritePID(pidFile, mainPID); err != nil {
+	if err != nil {
 		return fmt.Errorf("failed to write pit pid: %w", err)
 	}
+	e.pidLock = lock
+	e.startedAt = time.Now()
 
-	// ============================================
-	// AUTO HOSTS GENERATOR (SCAN /WWW → ADD DOMAINS)
-	// ============================================
-	wwwDir := filepath.Join(e.BasePath, "www")
-	entries, _ := os.ReadDir(wwwDir)
+	// www/<dir>.test + project domains → /etc/hosts
+	e.syncHosts()
 
-	var domains []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			domains = append(domains, entry.Name()+".test")
+	// tool vhosts point at fixed cert paths: renew them before nginx loads
+	if ca := certs.New(e.BasePath); ca.Exists() {
+		if _, err := ca.RenewExpiring(); err != nil {
+			fmt.Println("[Certs] Renewal failed:", err)
 		}
 	}
 
-	if len(domains) > 0 {
-		fmt.Println("[Hosts] Syncing domains...")
-		if err := util.EnsureHosts(domains); err != nil {
-			fmt.Println("[Hosts] Failed to update /etc/hosts:", err)
-		}
-	}
-	// ============================================
-
 	// start global services
 	for _, s := range e.Services {
 		fmt.Println("Starting:", s.Name())
 		if err := s.Start(); err != nil {
 			return err
 		}
+		events.Publish(events.Event{Type: events.ServiceStarted, Service: s.Name()})
 	}
 
 	fmt.Println("Using BasePath:", e.BasePath)
 	fmt.Println("Scanning WWW:", filepath.Join(e.BasePath, "www"))
 
+	// keep nginx routes + hosts in line with project changes
+	go e.watchProjects()
+	go e.watchCerts()
+	go e.watchCron()
+
 	fmt.Println("pit running at http://localhost:8080")
 	return nil
 }
 
+// ============================================
+// AUTO HOSTS GENERATOR (WWW + PROJECT DOMAINS)
+// ============================================
+func (e *Engine) syncHosts() {
+	domains := hostsDomains(e.BasePath)
+	if len(domains) == 0 {
+		return
+	}
+
+	fmt.Println("[Hosts] Syncing domains...")
+	if err := util.EnsureHosts(domains); err != nil {
+		fmt.Println("[Hosts] Failed to update /etc/hosts:", err)
+		return
+	}
+	events.Publish(events.Event{
+		Type: events.HostsUpdated,
+		Data: map[string]any{"domains": domains},
+	})
+}
+
+// certCheckInterval is how often leaf certificates are checked for
+// renewal while the engine runs.
+const certCheckInterval = 12 * time.Hour
+
+// watchCerts renews leaf certificates nearing expiry and reloads nginx
+// so long-running engines never serve an expired certificate.
+func (e *Engine) watchCerts() {
+	ca := certs.New(e.BasePath)
+	t := time.NewTicker(certCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-t.C:
+			if !ca.Exists() {
+				continue
+			}
+			n, err := ca.RenewExpiring()
+			if err != nil {
+				fmt.Println("[Certs] Renewal failed:", err)
+			}
+			if n > 0 {
+				if err := e.ReloadNginx(); err != nil {
+					fmt.Println("[Certs] nginx reload failed:", err)
+				}
+			}
+		}
+	}
+}
+
+// watchProjects regenerates the global nginx proxies and hosts entries
+// whenever a project is created, deleted or reconfigured, until the
+// engine stops.
+func (e *Engine) watchProjects() {
+	sub := events.Subscribe(64)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			switch ev.Type {
+			case events.ProjectCreated, events.ProjectDeleted, events.ProjectConfigUpdated:
+				fmt.Println("[Domains] Project", ev.Project, "changed, updating routes")
+				e.syncHosts()
+				if err := e.ReloadNginx(); err != nil {
+					fmt.Println("[Domains] nginx reload failed:", err)
+				}
+			}
+		}
+	}
+}
+
 func (e *Engine) StopAll() error {
 	fmt.Println("=== pit STOP ===")
 
@@ -167,19 +284,60 @@ func (e *Engine) StopAll() error {
 	for _, s := range e.Services {
 		fmt.Println("Stopping:", s.Name())
 		_ = s.Stop()
+		events.Publish(events.Event{Type: events.ServiceStopped, Service: s.Name()})
 	}
 
 	// kill project runtimes
 	fmt.Println("[ForceKill] Cleaning all project runtimes ...")
 	KillAllProjectRuntimes(e.BasePath)
+	e.stopPHPMasters()
+
+	// anything else this process still supervises (project nginx, ...)
+	services.DefaultSupervisor.StopAll()
+
+	if e.pidLock != nil {
+		// we are the engine: drop our pid file and let the caller exit
+		e.pidLock.Release()
+		e.pidLock = nil
+	} else {
+		// kill pit main process (jika dipanggil dari luar)
+		KillPID(e.BasePath, e.PIDFile())
+	}
 
-	// kill pit main process (jika dipanggil dari luar)
-	mainPIDFile := filepath.Join(e.BasePath, "runtime", "pit.pid")
-	KillPID(mainPIDFile)
+	e.doneOnce.Do(func() { close(e.done) })
 
 	fmt.Println("pit stopped cleanly.")
 	return nil
 }
+
+// Done is closed once StopAll has torn the stack down in this process
+// (signal handler, API /stop, ...).
+func (e *Engine) Done() <-chan struct{} {
+	return e.done
+}
+
+// ReloadConfig re-reads config/engine.json and applies it to the
+// running stack: switches PHP-FPM if the version changed and
+// regenerates + reloads the global nginx (picks up new www sites).
+func (e *Engine) ReloadConfig() error {
+	fmt.Println("=== pit RELOAD ===")
+
+	cfgPath := filepath.Join(e.BasePath, "config", "engine.json")
+	cfg := config.Load(cfgPath)
+
+	oldVersion := e.Config.PHPVersion
+	e.Config = cfg
+	e.applyConfig()
+
+	if cfg.PHPVersion != oldVersion {
+		fmt.Println("[Reload] PHP version", oldVersion, "→", cfg.PHPVersion)
+		if err := e.switchPHPVersion(oldVersion, cfg.PHPVersion); err != nil {
+			return err
+		}
+	}
+
+	return e.ReloadNginx()
+}
 func (e *Engine) ReloadNginx() error {
 	for _, s := range e.Services {
 		if s.Name() == "nginx" {
@@ -194,8 +352,68 @@ func (e *Engine) ReloadNginx() error {
 	return fmt.Errorf("nginx service not found")
 }
 
+// SyncTools scans tools/*, writes their vhosts and hosts entries and
+// reloads the global nginx.
+func (e *Engine) SyncTools() error {
+	mgr := tools.Manager{
+		Base:       e.BasePath,
+		PhpSockAbs: e.ToolsPHPSocket(),
+
+		NginxReload: func() error {
+			return e.ReloadNginx()
+		},
+	}
+	return mgr.SyncAll()
+}
+
 // ---------- STATUS ----------
 
+// EngineStatus is the full picture served to `pit status`.
+type EngineStatus = pitapi.EngineStatus
+
+func (e *Engine) PIDFile() string {
+	return filepath.Join(e.BasePath, "runtime", "pit.pid")
+}
+
+func (e *Engine) LogFile() string {
+	return filepath.Join(e.BasePath, "runtime", "logs", "pit.log")
+}
+
+// Running reports whether an engine (this or another process) holds
+// the pid lock, and its pid.
+func (e *Engine) Running() (int, bool) {
+	if !LockHeld(e.PIDFile()) {
+		return 0, false
+	}
+	pid, _ := ReadPID(e.PIDFile())
+	return pid, true
+}
+
+// FullStatus reports every global service and every project's services.
+func (e *Engine) FullStatus() EngineStatus {
+	st := EngineStatus{
+		PID:        os.Getpid(),
+		PHPVersion: e.Config.PHPVersion,
+		Services:   e.ServiceStatuses(),
+		Projects:   map[string]map[string]services.ServiceStatus{},
+	}
+	if !e.startedAt.IsZero() {
+		st.Uptime = int64(time.Since(e.startedAt).Seconds())
+	}
+
+	reg := NewProjectRegistry(e.BasePath)
+	names, _ := reg.List()
+	for _, name := range names {
+		peng, err := reg.Load(name)
+		if err != nil {
+			continue
+		}
+		st.Projects[name] = peng.Status()
+	}
+
+	return st
+}
+
 // Return status tiap service (running / stopped)
 func (e *Engine) ServiceStatuses() map[string]services.ServiceStatus {
 	statuses := make(map[string]services.ServiceStatus)
@@ -203,13 +421,28 @@ func (e *Engine) ServiceStatuses() map[string]services.ServiceStatus {
 	for _, s := range e.Services {
 		statuses[s.Name()] = s.Status()
 	}
+	for _, m := range services.PHPMasters(e.BasePath) {
+		statuses[m.Name()] = m.Status()
+	}
 
 	return statuses
 }
 
+// stopPHPMasters stops the on-demand FPM masters of project PHP
+// versions (see services.EnsurePHPMaster).
+func (e *Engine) stopPHPMasters() {
+	for _, m := range services.PHPMasters(e.BasePath) {
+		fmt.Println("Stopping:", m.Name())
+		_ = m.Stop()
+		events.Publish(events.Event{Type: events.ServiceStopped, Service: m.Name()})
+	}
+}
+
 // ---------- HELPERS (PID & PORT) ----------
 
-func killPidFile(pidFile string) {
+// stop the process in pidFile with the shared graceful routine
+// (sig → util.StopTimeout → SIGKILL)
+func killPidFile(base, pidFile string, sig syscall.Signal) {
 	data, err := os.ReadFile(pidFile)
 	if err != nil {
 		return
@@ -227,29 +460,19 @@ func killPidFile(pidFile string) {
 		return
 	}
 
-	proc, err := os.FindProcess(pid)
-	if err == nil {
-		_ = proc.Kill()
+	// stale pid files may point at a recycled pid: only stop our own
+	if procfs.OwnedBy(pid, base) {
+		res, _ := util.StopProcess(pid, sig, 0, nil)
+		fmt.Printf("[Stop] %s: %s\n", filepath.Base(pidFile), res)
 	}
 
 	_ = os.Remove(pidFile)
 }
 
-// kill proses berdasarkan port (fallback paling brutal)
-func killPort(port int) {
-	out, err := exec.Command("lsof", "-t", "-i", fmt.Sprintf(":%d", port)).Output()
-	if err != nil {
-		return
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		_ = exec.Command("kill", "-9", line).Run()
-	}
+// kill proses berdasarkan port (fallback paling brutal), tapi hanya
+// proses yang binary-nya ada di bawah base
+func killPort(base string, port int) {
+	util.KillPort(base, port)
 }
 
 // ========== PUBLIC: FORCE CLEANUP (dipakai oleh main.go/API) ==========
@@ -260,6 +483,7 @@ func (e *Engine) ForceKillAllProjectRuntimes() {
 
 	// 1) cleanup normal
 	e.cleanupProjectRuntimes()
+	e.stopPHPMasters()
 
 	// 2) scan semua project config
 	projectsDir := filepath.Join(e.BasePath, "projects")
@@ -282,8 +506,7 @@ func (e *Engine) ForceKillAllProjectRuntimes() {
 		}
 
 		// Kill runtime ports
-		killPort(cfg.Port)
-		killPort(cfg.Port + 100)
+		killPort(e.BasePath, cfg.Port)
 
 		// Runtime paths
 		projectRuntime := filepath.Join(e.BasePath, "runtime", project)
@@ -291,8 +514,8 @@ func (e *Engine) ForceKillAllProjectRuntimes() {
 		phpDir := filepath.Join(projectRuntime, "php")
 
 		// Kill PID files
-		killPidFile(filepath.Join(runDir, "php-fpm.pid"))
-		killPidFile(filepath.Join(runDir, "nginx.pid"))
+		killPidFile(e.BasePath, filepath.Join(runDir, "php-fpm.pid"), util.GracefulSignal("php-fpm"))
+		killPidFile(e.BasePath, filepath.Join(runDir, "nginx.pid"), util.GracefulSignal("nginx"))
 
 		// 🔥 REMOVE SOCKET (ROOT CAUSE BUG)
 		removeSocket(filepath.Join(phpDir, "php-fpm.sock"))
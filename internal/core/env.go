@@ -0,0 +1,122 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	util "pit/internal/utils"
+)
+
+// -----------------------------------------------------------
+// PROJECT ENVIRONMENT
+// -----------------------------------------------------------
+//
+// A project's env is assembled from, in order (later wins):
+//
+//	env_files       dotenv files in the project (.env, .env.local, ...)
+//	env             the map in .pit/config.json
+//	.pit/secrets.env  local secrets, kept out of git by .pit/.gitignore
+//
+// The result goes into the FPM pool (env[KEY]), the proxy command,
+// sidecars and cron jobs.
+
+// SecretsFile lives in .pit/ and is never committed.
+const SecretsFile = "secrets.env"
+
+// ResolveEnv merges the env sources of the project. Warnings name keys
+// set by more than one source with different values, and env files that
+// do not exist.
+func (cfg *ProjectConfig) ResolveEnv(base string) (map[string]string, []string, error) {
+	projectDir := filepath.Join(base, "projects", cfg.Name)
+
+	env := map[string]string{}
+	from := map[string]string{}
+	var warnings []string
+
+	merge := func(source string, vars map[string]string) {
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if prev, ok := from[k]; ok && env[k] != vars[k] {
+				warnings = append(warnings, fmt.Sprintf("%s from %s is overridden by %s", k, prev, source))
+			}
+			env[k] = vars[k]
+			from[k] = source
+		}
+	}
+
+	for _, f := range cfg.EnvFiles {
+		vars, err := util.ReadDotenv(filepath.Join(projectDir, f))
+		if os.IsNotExist(err) {
+			warnings = append(warnings, fmt.Sprintf("env file %s not found", f))
+			continue
+		}
+		if err != nil {
+			return nil, warnings, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		merge(f, vars)
+	}
+
+	merge(".pit/config.json", cfg.Env)
+
+	secrets := filepath.Join(projectDir, ".pit", SecretsFile)
+	vars, err := util.ReadDotenv(secrets)
+	switch {
+	case err == nil:
+		ensureSecretsIgnored(projectDir)
+		merge(".pit/"+SecretsFile, vars)
+	case !os.IsNotExist(err):
+		return nil, warnings, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	// pit sets these for proxy commands; the project value wins, but
+	// point it out since it usually breaks the upstream
+	if cfg.IsProxy() {
+		for _, k := range []string{"PORT", "HOST"} {
+			if src, ok := from[k]; ok {
+				warnings = append(warnings, fmt.Sprintf("%s from %s overrides the upstream pit sets", k, src))
+			}
+		}
+	}
+
+	return env, warnings, nil
+}
+
+func validateEnv(cfg *ProjectConfig) error {
+	for k := range cfg.Env {
+		if !envKeyRe.MatchString(k) {
+			return fmt.Errorf("%w: env name %q", ErrInvalidConfig, k)
+		}
+	}
+	for _, f := range cfg.EnvFiles {
+		if f = filepath.Clean(f); f == "." || filepath.IsAbs(f) || f == ".." || strings.HasPrefix(f, "../") {
+			return fmt.Errorf("%w: env file %q must be inside the project", ErrInvalidConfig, f)
+		}
+	}
+	return nil
+}
+
+// ensureSecretsIgnored adds secrets.env to .pit/.gitignore.
+func ensureSecretsIgnored(projectDir string) {
+	path := filepath.Join(projectDir, ".pit", ".gitignore")
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) == SecretsFile {
+				return
+			}
+		}
+		if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+			data = append(data, '\n')
+		}
+	}
+	_ = os.WriteFile(path, append(data, SecretsFile+"\n"...), 0o644)
+}
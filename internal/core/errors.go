@@ -0,0 +1,26 @@
+package core
+
+import "errors"
+
+// Typed errors returned by the registry/engine. Callers (API, CLI) map
+// them with errors.Is; messages are wrapped with the offending value.
+var (
+	ErrProjectNotFound    = errors.New("project not found")
+	ErrProjectExists      = errors.New("project already exists")
+	ErrInvalidProjectName = errors.New("invalid project name")
+	ErrInvalidPort        = errors.New("invalid port")
+	ErrPHPVersionNotFound = errors.New("php version not found")
+	ErrPortConflict       = errors.New("port already assigned")
+	ErrPortInUse          = errors.New("port in use")
+	ErrNoFreePort         = errors.New("no free port")
+	ErrInvalidDomain      = errors.New("invalid domain")
+	ErrDomainConflict     = errors.New("domain already in use")
+	ErrInvalidFramework   = errors.New("invalid framework")
+	ErrInvalidRoot        = errors.New("invalid document root")
+	ErrInvalidConfig      = errors.New("invalid project config")
+	ErrExtensionNotFound  = errors.New("php extension not found")
+	ErrExtensionLocked    = errors.New("php extension not managed by pit")
+	ErrInvalidBuild       = errors.New("invalid build")
+	ErrBuildExists        = errors.New("build already installed")
+	ErrBuildInUse         = errors.New("build in use")
+)
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pit/internal/templates"
+)
+
+// -----------------------------------------------------------
+// FRAMEWORK + DOCUMENT ROOT
+// -----------------------------------------------------------
+
+// Site resolves the framework (pinned or detected) and the absolute
+// document root the project nginx serves.
+func (cfg *ProjectConfig) Site(base string) (framework, docRoot string) {
+	return templates.Resolve(filepath.Join(base, "projects", cfg.Name), cfg.Framework, cfg.Root)
+}
+
+// ValidateFramework accepts "" (detect) or a framework with a
+// locations template (built in or in config/templates/locations/).
+func ValidateFramework(base, fw string) error {
+	set := templates.New(base)
+	if fw == "" || set.Valid(fw) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q (known: %s)", ErrInvalidFramework, fw, strings.Join(set.Frameworks(), ", "))
+}
+
+// validateRoot cleans a document root and makes sure it is an existing
+// directory inside the project.
+func (r *ProjectRegistry) validateRoot(name, root string) (string, error) {
+	root = filepath.Clean(strings.TrimSpace(root))
+	if filepath.IsAbs(root) || root == ".." || strings.HasPrefix(root, "../") {
+		return "", fmt.Errorf("%w: %q must be relative to the project", ErrInvalidRoot, root)
+	}
+
+	st, err := os.Stat(filepath.Join(r.projectPath(name), root))
+	if err != nil || !st.IsDir() {
+		return "", fmt.Errorf("%w: %s is not a directory in project %s", ErrInvalidRoot, root, name)
+	}
+	return root, nil
+}
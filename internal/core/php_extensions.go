@@ -0,0 +1,337 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"pit/internal/services"
+	"pit/pkg/pitapi"
+)
+
+// -----------------------------------------------------------
+// PHP EXTENSIONS (per version + per project)
+// -----------------------------------------------------------
+//
+// Shared extensions are the .so files in the build's extension_dir
+// (php/<ver>/lib/php/extensions/<api>/). Enabling one for the version
+// writes php/<ver>/etc/conf.d/<ext>.ini (PHP_INI_SCAN_DIR of every php
+// pit starts); enabling it for a project adds it to the project config,
+// loaded with php_admin_value[extension] in that pool only.
+
+type PHPExtension = pitapi.PHPExtension
+
+// loaded with zend_extension=, never per pool
+var zendExtensions = map[string]bool{"opcache": true, "xdebug": true}
+
+var extLineRe = regexp.MustCompile(`^\s*(zend_)?extension\s*=\s*"?([^";\s]+)`)
+
+func phpBase(base, version string) string {
+	return filepath.Join(base, "php", version)
+}
+
+func checkPHPVersion(base, version string) error {
+	st, err := os.Stat(phpBase(base, version))
+	if err != nil || !st.IsDir() {
+		return fmt.Errorf("%w: %s", ErrPHPVersionNotFound, version)
+	}
+	return nil
+}
+
+// extensionDir is the first php/<ver>/lib/php/extensions/* directory.
+func extensionDir(base, version string) string {
+	dirs, _ := filepath.Glob(filepath.Join(phpBase(base, version), "lib", "php", "extensions", "*"))
+	sort.Strings(dirs)
+	for _, d := range dirs {
+		if st, err := os.Stat(d); err == nil && st.IsDir() {
+			return d
+		}
+	}
+	return ""
+}
+
+// sharedExtensions maps extension name → .so path.
+func sharedExtensions(base, version string) map[string]string {
+	out := map[string]string{}
+	dir := extensionDir(base, version)
+	if dir == "" {
+		return out
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.so"))
+	for _, f := range files {
+		out[extName(f)] = f
+	}
+	return out
+}
+
+// extName normalizes "redis", "redis.so", "/x/php_redis.so" to "redis".
+func extName(s string) string {
+	s = strings.TrimSuffix(filepath.Base(s), ".so")
+	return strings.ToLower(strings.TrimPrefix(s, "php_"))
+}
+
+func extFragment(base, version, ext string) string {
+	return filepath.Join(services.PHPScanDir(phpBase(base, version)), ext+".ini")
+}
+
+// iniEnabled lists extensions loaded by php.ini and conf.d/*.ini, with
+// the file that loads them.
+func iniEnabled(base, version string) map[string]string {
+	etc := filepath.Join(phpBase(base, version), "etc")
+	files := []string{filepath.Join(etc, "php.ini")}
+	frags, _ := filepath.Glob(filepath.Join(services.PHPScanDir(phpBase(base, version)), "*.ini"))
+	sort.Strings(frags)
+	files = append(files, frags...)
+
+	out := map[string]string{}
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if m := extLineRe.FindStringSubmatch(sc.Text()); m != nil {
+				out[extName(m[2])] = path
+			}
+		}
+		f.Close()
+	}
+	return out
+}
+
+// builtinExtensions asks the CLI binary (without any ini) which modules
+// are compiled in. Empty when the build has no bin/php.
+func builtinExtensions(base, version string) map[string]bool {
+	out := map[string]bool{}
+	bin := filepath.Join(phpBase(base, version), "bin", "php")
+	data, err := exec.Command(bin, "-n", "-m").Output()
+	if err != nil {
+		return out
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "[") {
+			continue
+		}
+		out[strings.ToLower(strings.ReplaceAll(line, " ", "_"))] = true
+	}
+	return out
+}
+
+// ListPHPExtensions describes every extension of a PHP build: shared
+// or compiled in, enabled for the version, and per-project use.
+func ListPHPExtensions(base, version string) ([]PHPExtension, error) {
+	if err := checkPHPVersion(base, version); err != nil {
+		return nil, err
+	}
+
+	shared := sharedExtensions(base, version)
+	enabled := iniEnabled(base, version)
+	builtin := builtinExtensions(base, version)
+
+	projects := map[string][]string{}
+	names, _ := NewProjectRegistry(base).List()
+	for _, p := range names {
+		cfg, err := LoadProjectConfig(base, p)
+		if err != nil || cfg.PHPVersion != version {
+			continue
+		}
+		for _, ext := range cfg.Extensions {
+			projects[ext] = append(projects[ext], p)
+		}
+	}
+
+	all := map[string]bool{}
+	for _, m := range []map[string]string{shared, enabled} {
+		for k := range m {
+			all[k] = true
+		}
+	}
+	for k := range builtin {
+		all[k] = true
+	}
+
+	var out []PHPExtension
+	for name := range all {
+		_, isShared := shared[name]
+		_, isEnabled := enabled[name]
+		out = append(out, PHPExtension{
+			Name:     name,
+			Shared:   isShared,
+			Zend:     zendExtensions[name],
+			Builtin:  builtin[name],
+			Enabled:  isEnabled || builtin[name],
+			Projects: projects[name],
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// PHPExtensionInfo is the ListPHPExtensions entry of one extension.
+func PHPExtensionInfo(base, version, ext string) (*PHPExtension, error) {
+	list, err := ListPHPExtensions(base, version)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Name == extName(ext) {
+			return &list[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s (php %s)", ErrExtensionNotFound, ext, version)
+}
+
+// EnablePHPExtension loads ext for every pool of the version through a
+// conf.d fragment, reloads the version's FPM master and restarts the
+// projects a reload does not reach (see restartExtensionUsers).
+func EnablePHPExtension(base, version, ext string) error {
+	if err := checkPHPVersion(base, version); err != nil {
+		return err
+	}
+	ext = extName(ext)
+
+	so, ok := sharedExtensions(base, version)[ext]
+	if !ok {
+		if builtinExtensions(base, version)[ext] {
+			return nil // compiled in, always on
+		}
+		return fmt.Errorf("%w: %s (php %s)", ErrExtensionNotFound, ext, version)
+	}
+	if from, ok := iniEnabled(base, version)[ext]; ok {
+		if from != extFragment(base, version, ext) {
+			return nil // already loaded by php.ini or another fragment
+		}
+	}
+
+	directive := "extension"
+	if zendExtensions[ext] {
+		directive = "zend_extension"
+	}
+
+	frag := extFragment(base, version, ext)
+	if err := os.MkdirAll(filepath.Dir(frag), 0o755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("; managed by pit php ext\n%s=%s\n", directive, so)
+	if err := os.WriteFile(frag, []byte(content), 0o644); err != nil {
+		return err
+	}
+
+	reloadPHPMaster(base, version)
+	restartExtensionUsers(base, version, ext)
+	return nil
+}
+
+// DisablePHPExtension removes the conf.d fragment of ext. Extensions
+// loaded by php.ini itself are left alone and reported.
+func DisablePHPExtension(base, version, ext string) error {
+	if err := checkPHPVersion(base, version); err != nil {
+		return err
+	}
+	ext = extName(ext)
+
+	from, ok := iniEnabled(base, version)[ext]
+	switch {
+	case builtinExtensions(base, version)[ext]:
+		return fmt.Errorf("%w: %s is compiled into php %s", ErrExtensionLocked, ext, version)
+	case !ok:
+		return nil
+	case from != extFragment(base, version, ext):
+		return fmt.Errorf("%w: %s is loaded by %s, edit that file", ErrExtensionLocked, ext, from)
+	}
+
+	if err := os.Remove(from); err != nil {
+		return err
+	}
+	reloadPHPMaster(base, version)
+	restartExtensionUsers(base, version, ext)
+	return nil
+}
+
+func reloadPHPMaster(base, version string) {
+	if err := services.ReloadPHPFPM(base, version); err != nil {
+		fmt.Println("[PHP] reload skipped:", err)
+	}
+}
+
+// restartExtensionUsers restarts the running projects of the version
+// whose PHP a master reload does not update: those with their own
+// master (debug) and those whose pool loads ext itself, so the pool's
+// php_admin_value[extension] follows the version-wide state.
+func restartExtensionUsers(base, version, ext string) {
+	reg := NewProjectRegistry(base)
+	names, _ := reg.List()
+	for _, name := range names {
+		cfg, err := LoadProjectConfig(base, name)
+		if err != nil || cfg.PHPVersion != version || cfg.IsProxy() {
+			continue
+		}
+		if cfg.Debug == nil && !slices.Contains(cfg.Extensions, ext) {
+			continue
+		}
+		peng, err := reg.Load(name)
+		if err != nil || !peng.Running() {
+			continue
+		}
+
+		fmt.Printf("[PHP] restarting %s (%s changed for php %s)\n", name, ext, version)
+		if err := reg.Restart(name); err != nil {
+			fmt.Printf("[PHP] %s: restart failed: %v\n", name, err)
+		}
+	}
+}
+
+// SetProjectExtension adds or removes ext from the project's own
+// extensions and restarts it.
+func (r *ProjectRegistry) SetProjectExtension(name, ext string, on bool) (*ProjectConfig, error) {
+	cfg, err := r.ReadConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	ext = extName(ext)
+
+	var list []string
+	for _, e := range cfg.Extensions {
+		if e != ext {
+			list = append(list, e)
+		}
+	}
+	if on {
+		list = append(list, ext)
+	}
+	return r.Update(name, ProjectConfigPatch{Extensions: &list})
+}
+
+// validateExtensions checks per-project extensions against the
+// project's PHP build and returns the .so paths the pool still has to
+// load (not those already enabled for the whole version).
+func validateExtensions(base string, cfg *ProjectConfig) ([]string, error) {
+	if len(cfg.Extensions) == 0 {
+		return nil, nil
+	}
+
+	shared := sharedExtensions(base, cfg.PHPVersion)
+	global := iniEnabled(base, cfg.PHPVersion)
+	var paths []string
+	for _, ext := range cfg.Extensions {
+		if zendExtensions[ext] {
+			return nil, fmt.Errorf("%w: %s is a zend extension and can only be enabled for the whole version", ErrInvalidConfig, ext)
+		}
+		so, ok := shared[ext]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s (php %s)", ErrExtensionNotFound, ext, cfg.PHPVersion)
+		}
+		if _, ok := global[ext]; !ok {
+			paths = append(paths, so)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pit/pkg/pitapi"
+)
+
+// -----------------------------------------------------------
+// PHP.INI OVERRIDES + FPM POOL TUNING
+// -----------------------------------------------------------
+
+type IniValue = pitapi.IniValue
+
+var (
+	iniKeyRe   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	durationRe = regexp.MustCompile(`^\d+[smhd]?$`)
+)
+
+type poolKind int
+
+const (
+	poolInt poolKind = iota
+	poolDuration
+	poolBool
+	poolMode
+)
+
+// pool directives a project may set, with their value kind
+var poolDirectives = map[string]poolKind{
+	"pm":                        poolMode,
+	"pm.max_children":           poolInt,
+	"pm.start_servers":          poolInt,
+	"pm.min_spare_servers":      poolInt,
+	"pm.max_spare_servers":      poolInt,
+	"pm.max_requests":           poolInt,
+	"pm.process_idle_timeout":   poolDuration,
+	"request_terminate_timeout": poolDuration,
+	"request_slowlog_timeout":   poolDuration,
+	"rlimit_files":              poolInt,
+	"catch_workers_output":      poolBool,
+}
+
+// pit's historical pool sizing
+var poolDefaults = map[string]int{
+	"pm.max_children":      5,
+	"pm.start_servers":     2,
+	"pm.min_spare_servers": 1,
+	"pm.max_spare_servers": 3,
+}
+
+// validatePHPSettings checks php_ini and pool and returns the pool
+// directives to render, with pit's defaults filled in.
+func validatePHPSettings(cfg *ProjectConfig) (map[string]string, error) {
+	for k, v := range cfg.PHPIni {
+		if !iniKeyRe.MatchString(k) {
+			return nil, fmt.Errorf("%w: php_ini key %q", ErrInvalidConfig, k)
+		}
+		if strings.ContainsAny(string(v), "\r\n") {
+			return nil, fmt.Errorf("%w: php_ini %s contains a newline", ErrInvalidConfig, k)
+		}
+	}
+
+	pool := map[string]string{"pm": "dynamic"}
+	for k, v := range cfg.Pool {
+		kind, ok := poolDirectives[k]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown pool directive %q (known: %s)", ErrInvalidConfig, k, strings.Join(poolDirectiveNames(), ", "))
+		}
+		val := strings.TrimSpace(string(v))
+		if err := checkPoolValue(k, kind, val); err != nil {
+			return nil, err
+		}
+		pool[k] = val
+	}
+
+	if err := checkPoolSizing(pool); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func checkPoolValue(key string, kind poolKind, val string) error {
+	switch kind {
+	case poolMode:
+		switch val {
+		case "static", "dynamic", "ondemand":
+			return nil
+		}
+		return fmt.Errorf("%w: pool pm %q (use static, dynamic or ondemand)", ErrInvalidConfig, val)
+	case poolInt:
+		if n, err := strconv.Atoi(val); err != nil || n < 0 {
+			return fmt.Errorf("%w: pool %s must be a number, got %q", ErrInvalidConfig, key, val)
+		}
+	case poolDuration:
+		if !durationRe.MatchString(val) {
+			return fmt.Errorf("%w: pool %s must be a duration like 30s or 5m, got %q", ErrInvalidConfig, key, val)
+		}
+	case poolBool:
+		switch strings.ToLower(val) {
+		case "yes", "no", "on", "off", "true", "false", "1", "0":
+			return nil
+		}
+		return fmt.Errorf("%w: pool %s must be yes or no, got %q", ErrInvalidConfig, key, val)
+	}
+	return nil
+}
+
+// checkPoolSizing fills in the default worker counts for the chosen pm
+// and rejects combinations php-fpm would refuse to load (a bad pool
+// takes every pool of that PHP version down with it).
+func checkPoolSizing(pool map[string]string) error {
+	num := func(k string) int {
+		n, _ := strconv.Atoi(pool[k])
+		return n
+	}
+	set := func(k string) bool {
+		_, ok := pool[k]
+		return ok
+	}
+
+	if !set("pm.max_children") {
+		pool["pm.max_children"] = strconv.Itoa(poolDefaults["pm.max_children"])
+	}
+	maxChildren := num("pm.max_children")
+	if maxChildren < 1 {
+		return fmt.Errorf("%w: pool pm.max_children must be at least 1", ErrInvalidConfig)
+	}
+
+	spare := []string{"pm.start_servers", "pm.min_spare_servers", "pm.max_spare_servers"}
+	if pool["pm"] != "dynamic" {
+		for _, k := range spare {
+			if set(k) {
+				return fmt.Errorf("%w: pool %s only applies to pm = dynamic", ErrInvalidConfig, k)
+			}
+		}
+		if pool["pm"] != "ondemand" && set("pm.process_idle_timeout") {
+			return fmt.Errorf("%w: pool pm.process_idle_timeout only applies to pm = ondemand", ErrInvalidConfig)
+		}
+		return nil
+	}
+	if set("pm.process_idle_timeout") {
+		return fmt.Errorf("%w: pool pm.process_idle_timeout only applies to pm = ondemand", ErrInvalidConfig)
+	}
+
+	// defaults shrink to fit a small max_children
+	if !set("pm.max_spare_servers") {
+		pool["pm.max_spare_servers"] = strconv.Itoa(min(poolDefaults["pm.max_spare_servers"], maxChildren))
+	}
+	if !set("pm.min_spare_servers") {
+		pool["pm.min_spare_servers"] = strconv.Itoa(min(poolDefaults["pm.min_spare_servers"], num("pm.max_spare_servers")))
+	}
+	if !set("pm.start_servers") {
+		start := max(num("pm.min_spare_servers"), min(poolDefaults["pm.start_servers"], num("pm.max_spare_servers")))
+		pool["pm.start_servers"] = strconv.Itoa(start)
+	}
+
+	minSpare, maxSpare, start := num("pm.min_spare_servers"), num("pm.max_spare_servers"), num("pm.start_servers")
+	switch {
+	case minSpare < 1:
+		return fmt.Errorf("%w: pool pm.min_spare_servers must be at least 1", ErrInvalidConfig)
+	case maxSpare > maxChildren:
+		return fmt.Errorf("%w: pool pm.max_spare_servers (%d) exceeds pm.max_children (%d)", ErrInvalidConfig, maxSpare, maxChildren)
+	case minSpare > maxSpare:
+		return fmt.Errorf("%w: pool pm.min_spare_servers (%d) exceeds pm.max_spare_servers (%d)", ErrInvalidConfig, minSpare, maxSpare)
+	case start < minSpare || start > maxSpare:
+		return fmt.Errorf("%w: pool pm.start_servers (%d) must be between min (%d) and max (%d) spare servers", ErrInvalidConfig, start, minSpare, maxSpare)
+	}
+	return nil
+}
+
+func poolDirectiveNames() []string {
+	names := make([]string, 0, len(poolDirectives))
+	for k := range poolDirectives {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func iniStrings(ini map[string]IniValue) map[string]string {
+	out := make(map[string]string, len(ini))
+	for k, v := range ini {
+		out[k] = string(v)
+	}
+	return out
+}
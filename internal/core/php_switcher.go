@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"pit/internal/events"
 	"pit/internal/services"
 )
 
@@ -15,11 +16,18 @@ import (
 // 4. Save config
 // 5. Rebuild PHP service
 // 6. Start versi baru
+// 7. Pool project yang masih di versi lama dapat master on demand
 func (e *Engine) SetPHPVersion(ver string) error {
+	return e.switchPHPVersion(e.Config.PHPVersion, ver)
+}
+
+// switchPHPVersion is SetPHPVersion from oldVer, the version the
+// running master serves (ReloadConfig has already replaced e.Config).
+func (e *Engine) switchPHPVersion(oldVer, ver string) error {
 	verPath := filepath.Join(e.BasePath, "php", ver)
 	info, err := os.Stat(verPath)
 	if err != nil || !info.IsDir() {
-		return fmt.Errorf("php version directory not found: %s", verPath)
+		return fmt.Errorf("%w: %s", ErrPHPVersionNotFound, verPath)
 	}
 
 	// Stop old php-fpm
@@ -49,7 +57,20 @@ func (e *Engine) SetPHPVersion(ver string) error {
 	// Start new PHP-FPM
 	for _, s := range e.Services {
 		if s.Name() == "php-fpm" {
-			return s.Start()
+			if err := s.Start(); err != nil {
+				return err
+			}
+			// projects pinned to the old version keep their pools
+			if oldVer != "" && oldVer != ver && services.PHPPools(e.BasePath, oldVer) > 0 {
+				if err := services.EnsurePHPMaster(e.BasePath, oldVer); err != nil {
+					fmt.Println("[PHP] master", oldVer, "not started:", err)
+				}
+			}
+			events.Publish(events.Event{
+				Type: events.PHPVersionSwitched,
+				Data: map[string]any{"from": oldVer, "to": ver},
+			})
+			return nil
 		}
 	}
 
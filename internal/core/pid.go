@@ -1,14 +1,88 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+
+	"pit/internal/procfs"
+	util "pit/internal/utils"
 )
 
+var ErrAlreadyRunning = errors.New("pit already running")
+
+// PIDLock is an exclusive flock on <pidFile>.lock, held for the whole
+// lifetime of the engine. Whoever holds it owns <pidFile>.
+type PIDLock struct {
+	pidFile string
+	f       *os.File
+}
+
+// AcquirePIDLock takes the engine lock and atomically writes our pid
+// (temp file + rename). It fails with ErrAlreadyRunning when another
+// engine holds the lock.
+func AcquirePIDLock(pidFile string) (*PIDLock, error) {
+	if err := os.MkdirAll(filepath.Dir(pidFile), 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(pidFile+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrAlreadyRunning
+		}
+		return nil, err
+	}
+
+	tmp := pidFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d", os.Getpid())), 0o644); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, pidFile); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &PIDLock{pidFile: pidFile, f: f}, nil
+}
+
+// LockHeld reports whether some engine currently holds the lock for
+// pidFile, without taking it.
+func LockHeld(pidFile string) bool {
+	f, err := os.Open(pidFile + ".lock")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return err == syscall.EWOULDBLOCK
+	}
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return false
+}
+
+// Release removes the pid file and drops the lock.
+func (l *PIDLock) Release() {
+	if l == nil || l.f == nil {
+		return
+	}
+	_ = os.Remove(l.pidFile)
+	_ = syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	_ = l.f.Close()
+	l.f = nil
+}
+
 func WritePID(pidFile string, pid int) error {
 	if err := os.MkdirAll(filepath.Dir(pidFile), 0o755); err != nil {
 		return err
@@ -28,31 +102,24 @@ func ReadPID(pidFile string) (int, error) {
 	return pid, nil
 }
 
-func KillPID(pidFile string) {
+// KillPID stops the process recorded in pidFile (SIGTERM, escalating to
+// SIGKILL after util.StopTimeout) if it runs a binary under base, then
+// removes the pid file.
+func KillPID(base, pidFile string) {
 	pid, err := ReadPID(pidFile)
 	if err != nil {
 		return
 	}
 
-	proc, _ := os.FindProcess(pid)
-	proc.Kill()
+	if procfs.OwnedBy(pid, base) {
+		res, _ := util.StopProcess(pid, syscall.SIGTERM, 0, nil)
+		fmt.Println("[Stop] pit:", res)
+	}
 
 	_ = os.Remove(pidFile)
 }
 
-// kill all processes on a port (brutal fallback)
-func KillPort(port int) {
-	out, err := exec.Command("lsof", "-t", "-i", fmt.Sprintf(":%d", port)).Output()
-	if err != nil {
-		return
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		exec.Command("kill", "-9", line).Run()
-	}
+// kill pit-owned processes on a port (brutal fallback)
+func KillPort(base string, port int) {
+	util.KillPort(base, port)
 }
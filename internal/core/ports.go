@@ -0,0 +1,284 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+
+	"pit/internal/procfs"
+)
+
+// -----------------------------------------------------------
+// PROJECT PORT ALLOCATOR (runtime/ports.json)
+// -----------------------------------------------------------
+//
+// One port per project. New projects get the lowest port of the range
+// that is neither assigned nor listening; set-port goes through Reserve
+// so two projects never share a port. The file is guarded by an flock
+// so CLI and daemon can both use it.
+//
+// Upstream ports of proxy projects are not in the table (the backend
+// binds them, not pit) but are read from the project configs and never
+// handed out either.
+
+const (
+	PortRangeStart = 10000
+	PortRangeEnd   = 10999
+)
+
+// projects already reported as sharing a port (warn once per process)
+var sharedPortWarned sync.Map
+
+type PortAllocator struct {
+	BasePath string
+}
+
+type portTable struct {
+	Projects map[string]int `json:"projects"`
+}
+
+func NewPortAllocator(base string) *PortAllocator {
+	return &PortAllocator{BasePath: base}
+}
+
+func (a *PortAllocator) path() string {
+	return filepath.Join(a.BasePath, "runtime", "ports.json")
+}
+
+// Allocate returns the port assigned to name, assigning a free one if
+// it has none yet.
+func (a *PortAllocator) Allocate(name string) (int, error) {
+	var port int
+	err := a.update(func(t *portTable) error {
+		if p, ok := t.Projects[name]; ok {
+			port = p
+			return nil
+		}
+
+		taken := t.owners()
+		for p, owner := range a.upstreams() {
+			taken[p] = owner
+		}
+		for p := PortRangeStart; p <= PortRangeEnd; p++ {
+			if _, ok := taken[p]; ok || procfs.PortInUse(p) {
+				continue
+			}
+			t.Projects[name] = p
+			port = p
+			return nil
+		}
+		return fmt.Errorf("%w in %d-%d", ErrNoFreePort, PortRangeStart, PortRangeEnd)
+	})
+	return port, err
+}
+
+// Reserve moves name to port. It fails if another project owns the
+// port, it is a proxy project's upstream or something is already
+// listening on it.
+func (a *PortAllocator) Reserve(name string, port int) error {
+	return a.update(func(t *portTable) error {
+		if cur, ok := t.Projects[name]; ok && cur == port {
+			return nil
+		}
+		if owner, ok := t.owners()[port]; ok && owner != name {
+			return fmt.Errorf("%w: %d is used by project %q", ErrPortConflict, port, owner)
+		}
+		if owner, ok := a.upstreams()[port]; ok {
+			return fmt.Errorf("%w: %d is the upstream of project %q", ErrPortConflict, port, owner)
+		}
+		if pids := procfs.PortOwners(port); len(pids) > 0 {
+			return fmt.Errorf("%w: %d is held by pid %d (%s)", ErrPortInUse, port, pids[0], procfs.Comm(pids[0]))
+		}
+		if procfs.PortInUse(port) {
+			return fmt.Errorf("%w: %d", ErrPortInUse, port)
+		}
+
+		t.Projects[name] = port
+		return nil
+	})
+}
+
+// Release gives name's port back.
+func (a *PortAllocator) Release(name string) error {
+	return a.update(func(t *portTable) error {
+		delete(t.Projects, name)
+		return nil
+	})
+}
+
+// User returns a project other than except that uses port, as its
+// nginx port or as its upstream.
+func (a *PortAllocator) User(port int, except string) (string, bool) {
+	var user string
+	var ok bool
+	_ = a.update(func(t *portTable) error {
+		if owner, found := t.owners()[port]; found && owner != except {
+			user, ok = owner, true
+			return nil
+		}
+		for name, p := range a.upstreamsByProject() {
+			if p == port && name != except {
+				user, ok = name, true
+				return nil
+			}
+		}
+		return nil
+	})
+	return user, ok
+}
+
+// upstreamsByProject maps every proxy project to its upstream port.
+func (a *PortAllocator) upstreamsByProject() map[string]int {
+	out := map[string]int{}
+	entries, _ := os.ReadDir(filepath.Join(a.BasePath, "projects"))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		cfg, err := LoadProjectConfig(a.BasePath, e.Name())
+		if err == nil && cfg.IsProxy() && cfg.UpstreamPort > 0 {
+			out[e.Name()] = cfg.UpstreamPort
+		}
+	}
+	return out
+}
+
+// upstreams maps upstream port → proxy project (first by name when
+// two already share one).
+func (a *PortAllocator) upstreams() map[int]string {
+	byProject := a.upstreamsByProject()
+	names := make([]string, 0, len(byProject))
+	for name := range byProject {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := map[int]string{}
+	for _, name := range names {
+		if _, ok := out[byProject[name]]; !ok {
+			out[byProject[name]] = name
+		}
+	}
+	return out
+}
+
+func (t *portTable) owners() map[int]string {
+	out := make(map[int]string, len(t.Projects))
+	for name, p := range t.Projects {
+		out[p] = name
+	}
+	return out
+}
+
+// -----------------------------------------------------------
+// LOCKED LOAD / SAVE
+// -----------------------------------------------------------
+
+func (a *PortAllocator) update(fn func(*portTable) error) error {
+	path := a.path()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	t, err := a.load()
+	if err != nil {
+		return err
+	}
+	a.adopt(t)
+
+	if err := fn(t); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (a *PortAllocator) load() (*portTable, error) {
+	t := &portTable{Projects: map[string]int{}}
+
+	data, err := os.ReadFile(a.path())
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %w", a.path(), err)
+	}
+	if t.Projects == nil {
+		t.Projects = map[string]int{}
+	}
+	return t, nil
+}
+
+// adopt keeps the table in line with projects/: entries of deleted
+// projects are dropped, projects created before the allocator existed
+// are registered with their configured port. When two of those share
+// a port the first (by name) keeps it and the others are reported.
+func (a *PortAllocator) adopt(t *portTable) {
+	entries, err := os.ReadDir(filepath.Join(a.BasePath, "projects"))
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+
+	exists := map[string]bool{}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			exists[e.Name()] = true
+			names = append(names, e.Name())
+		}
+	}
+
+	for name := range t.Projects {
+		if !exists[name] {
+			delete(t.Projects, name)
+		}
+	}
+
+	sort.Strings(names)
+	owners := t.owners()
+	for _, name := range names {
+		if _, ok := t.Projects[name]; ok {
+			continue
+		}
+		cfg, err := LoadProjectConfig(a.BasePath, name)
+		if err != nil || cfg.Port == 0 {
+			continue
+		}
+		if owner, taken := owners[cfg.Port]; taken {
+			if _, seen := sharedPortWarned.LoadOrStore(name, true); seen {
+				continue
+			}
+			fmt.Printf("[Ports] project %s shares port %d with %s — run: pit project set-port %s <port>\n",
+				name, cfg.Port, owner, name)
+			continue
+		}
+		t.Projects[name] = cfg.Port
+		owners[cfg.Port] = name
+	}
+}
@@ -1,11 +1,15 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
+
+	"pit/internal/procfs"
+	"pit/internal/services"
 )
 
 // ------------------------------------
@@ -91,6 +95,40 @@ func (e *Engine) PreflightChecks() []CheckResult {
 	return results
 }
 
+// ConfigChecks turns an nginx config rejection into check results
+// (one per problem) so the CLI can print it like the preflight.
+func ConfigChecks(err error) ([]CheckResult, bool) {
+	var cfgErr *services.NginxConfigError
+	if !errors.As(err, &cfgErr) {
+		return nil, false
+	}
+
+	var results []CheckResult
+	for _, is := range cfgErr.Issues {
+		if is.Level == "warn" {
+			continue
+		}
+		name := "Nginx config"
+		if is.File != "" {
+			name = fmt.Sprintf("%s:%d", is.File, is.Line)
+		}
+		results = append(results, CheckResult{
+			Name:   name,
+			OK:     false,
+			Reason: is.Message,
+			Fix:    "check config/templates/ overrides and .pit/nginx/*.conf snippets",
+		})
+	}
+	if len(results) == 0 {
+		results = append(results, CheckResult{
+			Name:   "Nginx config",
+			OK:     false,
+			Reason: cfgErr.Output,
+		})
+	}
+	return results, true
+}
+
 // ------------------------------------
 // HELPERS
 // ------------------------------------
@@ -104,23 +142,10 @@ func hasNetBindCap(bin string) bool {
 	return len(strings.TrimSpace(string(out))) > 0
 }
 func isPortInUse(port int) bool {
-	cmd := exec.Command(
-		"ss",
-		"-H",   // no header
-		"-lnt", // listen, tcp, numeric
-		"sport", "=", ":"+strconv.Itoa(port),
-	)
-
-	out, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-
-	return len(strings.TrimSpace(string(out))) > 0
+	return procfs.PortInUse(port)
 }
 
 // check if a process exists
 func processRunning(name string) bool {
-	err := exec.Command("pgrep", name).Run()
-	return err == nil
+	return procfs.ProcessRunning(name)
 }
@@ -6,11 +6,62 @@ import (
 	"path/filepath"
 )
 
+// project types
+const (
+	ProjectTypePHP   = "php"
+	ProjectTypeProxy = "proxy"
+)
+
 type ProjectConfig struct {
 	Name       string `json:"name"`
 	PHPVersion string `json:"php_version"`
 	Port       int    `json:"port"`
 	Root       string `json:"root"`
+
+	// "php" (default, FPM pool) or "proxy": Command is supervised and
+	// the project nginx forwards to 127.0.0.1:UpstreamPort
+	Type         string `json:"type,omitempty"`
+	Command      string `json:"command,omitempty"`
+	UpstreamPort int    `json:"upstream_port,omitempty"`
+
+	// env for the pool / command / sidecars / cron, on top of the
+	// dotenv EnvFiles; see ResolveEnv
+	Env      map[string]string `json:"env,omitempty"`
+	EnvFiles []string          `json:"env_files,omitempty"`
+
+	// sidecars (queue workers, schedulers, asset watchers) run next to
+	// the project with its PHP on PATH
+	Processes []ProcessConfig `json:"processes,omitempty"`
+
+	// scheduled commands, run by the engine while the project runs
+	Cron []CronJob `json:"cron,omitempty"`
+
+	// php_value/php_admin_value overrides and pool tuning (pm,
+	// pm.max_children, request_terminate_timeout, ...); see php_settings.go
+	PHPIni map[string]IniValue `json:"php_ini,omitempty"`
+	Pool   map[string]IniValue `json:"pool,omitempty"`
+
+	// shared extensions loaded in this project's pool only, on top of
+	// the ones enabled for the PHP version; see php_extensions.go
+	Extensions []string `json:"extensions,omitempty"`
+
+	// Xdebug for this project only (own FPM master); see xdebug.go
+	Debug *DebugConfig `json:"debug,omitempty"`
+
+	// pinned nginx template (laravel, symfony, wordpress, ...); empty
+	// means detect from the project files
+	Framework string `json:"framework,omitempty"`
+
+	// hostnames routed to this project by the global nginx on :80;
+	// empty Domains means <name>.test. Aliases may be wildcards
+	// (*.myapp.test), which are routed but not written to /etc/hosts.
+	Domains []string `json:"domains,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// IsProxy reports whether the project runs its own backend command.
+func (cfg *ProjectConfig) IsProxy() bool {
+	return cfg.Type == ProjectTypeProxy
 }
 
 func (cfg *ProjectConfig) Save(base string) error {
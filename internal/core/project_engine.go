@@ -3,11 +3,15 @@ package core
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
 
+	"pit/internal/events"
+	"pit/internal/procfs"
 	"pit/internal/services"
+	util "pit/internal/utils"
 )
 
 type ProjectEngine struct {
@@ -17,6 +21,9 @@ type ProjectEngine struct {
 	ProjectRoot string // path ke public user
 	RuntimeRoot string // path ke runtime/{project}
 	Services    []services.Service
+
+	// env keys set twice, missing env files (printed on start)
+	EnvWarnings []string
 }
 
 // -----------------------------------------------------------
@@ -28,9 +35,25 @@ func NewProjectEngine(base, name string) (*ProjectEngine, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := validateProcesses(cfg.Processes); err != nil {
+		return nil, err
+	}
+	if err := validateCron(cfg.Cron); err != nil {
+		return nil, err
+	}
+	if err := validateEnv(cfg); err != nil {
+		return nil, err
+	}
+	env, warnings, err := cfg.ResolveEnv(base)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := validatePHPSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	projectRoot := filepath.Join(base, "projects", name)
-	publicDir := filepath.Join(projectRoot, cfg.Root)
+	framework, publicDir := cfg.Site(base)
 
 	runtimeRoot := filepath.Join(base, "runtime", name)
 
@@ -52,12 +75,40 @@ func NewProjectEngine(base, name string) (*ProjectEngine, error) {
 		Config:      cfg,
 		ProjectRoot: publicDir,
 		RuntimeRoot: runtimeRoot,
+		EnvWarnings: warnings,
 	}
 
-	e.Services = []services.Service{
-		services.NewProjectPHPService(base, name, cfg.PHPVersion, cfg.Port+100),
-		services.NewProjectNginxService(base, name, cfg.Port),
+	nginx := services.NewProjectNginxService(base, name, cfg.Port)
+	nginx.ServerNames = cfg.Hostnames()
+	nginx.Framework = framework
+	nginx.Root = publicDir
+
+	if cfg.IsProxy() {
+		// the backend replaces the FPM pool
+		app := services.NewCommandService(base, name, "app", cfg.Command, filepath.Join(base, "projects", name))
+		app.Env = mergeEnv(phpCLIEnv(base, cfg.PHPVersion), env)
+		app.Path = phpBinPath(base, cfg.PHPVersion)
+		app.Port = cfg.UpstreamPort
+		nginx.Upstream = cfg.UpstreamPort
+
+		e.Services = []services.Service{app, nginx}
+	} else {
+		php := services.NewProjectPHPService(base, name, cfg.PHPVersion)
+		php.Env = env
+		php.Pool = pool
+		php.INI = iniStrings(cfg.PHPIni)
+		if php.Extensions, err = validateExtensions(base, cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Debug != nil {
+			if err := applyXdebug(base, cfg, php); err != nil {
+				return nil, err
+			}
+		}
+
+		e.Services = []services.Service{php, nginx}
 	}
+	e.Services = append(e.Services, sidecars(base, cfg, env)...)
 
 	return e, nil
 }
@@ -68,15 +119,20 @@ func NewProjectEngine(base, name string) (*ProjectEngine, error) {
 func (e *ProjectEngine) Start() error {
 	fmt.Println("=== START PROJECT:", e.Name, "===")
 
+	for _, w := range e.EnvWarnings {
+		fmt.Println("[Env]", e.Name+":", w)
+	}
+
 	// Safety: kill leftover processes
 	e.killLeftovers()
 
-	// Start both services
+	// php/app + nginx, then the sidecars
 	for _, svc := range e.Services {
 		fmt.Println("Starting:", svc.Name())
 		if err := svc.Start(); err != nil {
 			return fmt.Errorf("error starting %s: %v", svc.Name(), err)
 		}
+		events.Publish(events.Event{Type: events.ServiceStarted, Project: e.Name, Service: svc.Name()})
 	}
 
 	return nil
@@ -88,10 +144,20 @@ func (e *ProjectEngine) Start() error {
 func (e *ProjectEngine) Stop() error {
 	fmt.Println("=== STOP PROJECT:", e.Name, "===")
 
-	// stop each service (php + nginx)
+	// stop each service (php/app + nginx + sidecars)
 	for _, svc := range e.Services {
 		fmt.Println("Stopping:", svc.Name())
 		_ = svc.Stop()
+		events.Publish(events.Event{Type: events.ServiceStopped, Project: e.Name, Service: svc.Name()})
+	}
+
+	// processes of an older config (proxy command, ...) and a pool
+	// left from before the project became a proxy
+	services.DefaultSupervisor.StopProject(e.Name)
+	if e.Config.IsProxy() {
+		if pool := services.NewProjectPHPService(e.BasePath, e.Name, e.Config.PHPVersion); pool.Configured() {
+			_ = pool.Stop()
+		}
 	}
 
 	// kill workers & free ports
@@ -126,44 +192,58 @@ func (e *ProjectEngine) ForceStopAll() {
 // KILL ALL ZOMBIE PROCESSES (nginx + php-fpm)
 // -----------------------------------------------------------
 func (e *ProjectEngine) killLeftovers() {
-
-	project := e.Name
-
-	// pattern runtime dir khusus project
-	runtimePattern := fmt.Sprintf("%s/runtime/%s", e.BasePath, project)
-
-	// ---- Kill php-fpm workers for this project
-	out, _ := exec.Command("bash", "-c",
-		fmt.Sprintf("ps aux | grep php-fpm | grep '%s' | awk '{print $2}'", project),
-	).Output()
-
-	for _, pid := range strings.Split(string(out), "\n") {
-		p := strings.TrimSpace(pid)
-		if p != "" {
-			exec.Command("kill", "-9", p).Run()
+	var pids []int
+
+	// ---- php-fpm workers of this project's pool (exact title match)
+	pool := services.PHPPoolName(e.Name)
+	pids = append(pids, procfs.Find(func(_ int, args []string) bool {
+		return len(args) == 3 && args[0] == "php-fpm:" && args[1] == "pool" && args[2] == pool
+	})...)
+
+	// ---- nginx masters started with this project's prefix: graceful
+	// stop first, their workers follow the master
+	nginxPrefix := filepath.Join(e.RuntimeRoot, "nginx")
+	masters := procfs.FindByArg("-p", nginxPrefix)
+	pids = append(pids, procfs.WithChildren(masters)...)
+
+	for _, pid := range masters {
+		if procfs.OwnedBy(pid, e.BasePath) {
+			_, _ = util.StopProcess(pid, util.GracefulSignal("nginx"), 0, nil)
 		}
 	}
 
-	// ---- Kill nginx master + workers for this project
-	out, _ = exec.Command("bash", "-c",
-		fmt.Sprintf("ps aux | grep nginx | grep '%s' | awk '{print $2}'", runtimePattern),
-	).Output()
-
-	for _, pid := range strings.Split(string(out), "\n") {
-		p := strings.TrimSpace(pid)
-		if p != "" {
-			exec.Command("kill", "-9", p).Run()
+	// ---- whatever is still around (orphaned workers)
+	for _, pid := range pids {
+		if procfs.OwnedBy(pid, e.BasePath) && !procfs.Exited(pid) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
 		}
 	}
 
-	// ---- Kill ports
-	exec.Command("bash", "-c",
-		fmt.Sprintf("lsof -t -i:%d | xargs -r kill -9", e.Config.Port),
-	).Run()
+	// ---- proxy backend and sidecars started by another pit process
+	// (process group from their pid files), including sidecars since
+	// removed from the config
+	labels := []string{"app"}
+	for _, p := range e.Config.Processes {
+		labels = append(labels, p.Name)
+	}
+	pidFiles, _ := filepath.Glob(filepath.Join(e.RuntimeRoot, "run", "*.pid"))
+	for _, f := range pidFiles {
+		label := strings.TrimSuffix(filepath.Base(f), ".pid")
+		if !reservedProcessNames[label] && !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	for _, label := range labels {
+		if res := services.StopCommandPID(e.BasePath, e.Name, label); res.Step != util.StopNotRunning {
+			fmt.Printf("[Stop] %s:%s: %s\n", label, e.Name, res)
+		}
+	}
 
-	exec.Command("bash", "-c",
-		fmt.Sprintf("lsof -t -i:%d | xargs -r kill -9", e.Config.Port+100),
-	).Run()
+	// ---- Kill ports (pit-owned listeners only)
+	util.KillPort(e.BasePath, e.Config.Port)
+	if e.Config.IsProxy() {
+		util.KillPort(e.BasePath, e.Config.UpstreamPort)
+	}
 }
 
 // -----------------------------------------------------------
@@ -181,5 +261,18 @@ func (e *ProjectEngine) Status() map[string]services.ServiceStatus {
 	for _, svc := range e.Services {
 		resp[svc.Name()] = svc.Status()
 	}
+	for name, st := range cronStatus(e.BasePath, e.Config) {
+		resp[name] = st
+	}
 	return resp
 }
+
+// Running reports whether the project nginx is up.
+func (e *ProjectEngine) Running() bool {
+	for _, svc := range e.Services {
+		if n, ok := svc.(*services.ProjectNginxService); ok {
+			return n.Status().Running
+		}
+	}
+	return false
+}
@@ -3,7 +3,8 @@ package core
 import (
 	"os"
 	"path/filepath"
-	"strconv"
+
+	util "pit/internal/utils"
 )
 
 func KillAllProjectRuntimes(base string) {
@@ -23,24 +24,11 @@ func KillAllProjectRuntimes(base string) {
 		projectRun := filepath.Join(runtimeDir, projectName, "run")
 
 		// kill php
-		killPid(filepath.Join(projectRun, "php-fpm.pid"))
+		killPidFile(base, filepath.Join(projectRun, "php-fpm.pid"), util.GracefulSignal("php-fpm"))
 
 		// kill nginx
-		killPid(filepath.Join(projectRun, "nginx.pid"))
+		killPidFile(base, filepath.Join(projectRun, "nginx.pid"), util.GracefulSignal("nginx"))
 
 		// cleanup optional
 	}
 }
-
-func killPid(pidFile string) {
-	data, err := os.ReadFile(pidFile)
-	if err != nil {
-		return
-	}
-
-	pid, _ := strconv.Atoi(string(data))
-	proc, _ := os.FindProcess(pid)
-	_ = proc.Kill()
-
-	os.Remove(pidFile)
-}
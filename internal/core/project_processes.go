@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"pit/internal/services"
+	"pit/pkg/pitapi"
+)
+
+// -----------------------------------------------------------
+// SIDECAR PROCESSES (queue workers, schedulers, watchers)
+// -----------------------------------------------------------
+
+type ProcessConfig = pitapi.ProcessConfig
+
+var processNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// names used by the project's own services and logs
+var reservedProcessNames = map[string]bool{
+	"app": true, "nginx": true, "php": true, "php-fpm": true, "cron": true,
+}
+
+func validateProcesses(procs []ProcessConfig) error {
+	seen := map[string]bool{}
+	for _, p := range procs {
+		switch {
+		case !processNameRe.MatchString(p.Name):
+			return fmt.Errorf("%w: process name %q (use a-z, 0-9, - and _)", ErrInvalidConfig, p.Name)
+		case reservedProcessNames[p.Name]:
+			return fmt.Errorf("%w: process name %q is reserved", ErrInvalidConfig, p.Name)
+		case seen[p.Name]:
+			return fmt.Errorf("%w: duplicate process %q", ErrInvalidConfig, p.Name)
+		case strings.TrimSpace(p.Command) == "":
+			return fmt.Errorf("%w: process %q has no command", ErrInvalidConfig, p.Name)
+		}
+		seen[p.Name] = true
+
+		if dir := filepath.Clean(p.Dir); filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			return fmt.Errorf("%w: process %q dir must be inside the project", ErrInvalidConfig, p.Name)
+		}
+		switch services.RestartPolicy(p.Restart) {
+		case "", services.RestartAlways, services.RestartOnFailure, services.RestartNever:
+		default:
+			return fmt.Errorf("%w: process %q restart %q (use always, on-failure or never)", ErrInvalidConfig, p.Name, p.Restart)
+		}
+		for k := range p.Env {
+			if !envKeyRe.MatchString(k) {
+				return fmt.Errorf("%w: process %q env name %q", ErrInvalidConfig, p.Name, k)
+			}
+		}
+	}
+	return nil
+}
+
+// sidecars builds a command service per configured process. They get
+// the resolved project env (process env wins) and the project's PHP on
+// PATH.
+func sidecars(base string, cfg *ProjectConfig, env map[string]string) []services.Service {
+	projectDir := filepath.Join(base, "projects", cfg.Name)
+
+	var out []services.Service
+	for _, p := range cfg.Processes {
+		svc := services.NewCommandService(base, cfg.Name, p.Name, p.Command, filepath.Join(projectDir, p.Dir))
+		svc.Policy = services.RestartAlways
+		if p.Restart != "" {
+			svc.Policy = services.RestartPolicy(p.Restart)
+		}
+		svc.Env = mergeEnv(phpCLIEnv(base, cfg.PHPVersion), env, p.Env)
+		svc.Path = phpBinPath(base, cfg.PHPVersion)
+		out = append(out, svc)
+	}
+	return out
+}
+
+// phpBinPath is php/<version>/bin if that version is installed.
+func phpBinPath(base, version string) []string {
+	if version == "" {
+		return nil
+	}
+	dir := filepath.Join(base, "php", version, "bin")
+	if _, err := os.Stat(dir); err != nil {
+		return nil
+	}
+	return []string{dir}
+}
+
+// phpCLIEnv points the pit php binary at the version's conf.d so the
+// CLI loads the same extensions as the FPM pools.
+func phpCLIEnv(base, version string) map[string]string {
+	if len(phpBinPath(base, version)) == 0 {
+		return nil
+	}
+	return map[string]string{
+		"PHP_INI_SCAN_DIR": services.PHPScanDir(filepath.Join(base, "php", version)),
+	}
+}
+
+func mergeEnv(maps ...map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// -----------------------------------------------------------
+// PROXY PROJECTS (node, go, python, ... backends)
+// -----------------------------------------------------------
+
+var envKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// applyProxy merges the type/command/upstream fields of patch into cfg
+// and validates the result.
+func (r *ProjectRegistry) applyProxy(cfg *ProjectConfig, patch ProjectConfigPatch) error {
+	if patch.Type == nil && patch.Command == nil && patch.UpstreamPort == nil {
+		return nil
+	}
+
+	if patch.Type != nil {
+		cfg.Type = *patch.Type
+		if cfg.Type == ProjectTypePHP {
+			cfg.Type = "" // default, keep config.json short
+		}
+	}
+	if patch.Command != nil {
+		cfg.Command = strings.TrimSpace(*patch.Command)
+	}
+	if patch.UpstreamPort != nil {
+		cfg.UpstreamPort = *patch.UpstreamPort
+	}
+
+	port := cfg.Port
+	if patch.Port != nil {
+		port = *patch.Port
+	}
+	return r.validateProxy(cfg, port)
+}
+
+func (r *ProjectRegistry) validateProxy(cfg *ProjectConfig, port int) error {
+	switch cfg.Type {
+	case "":
+		return nil
+	case ProjectTypeProxy:
+	default:
+		return fmt.Errorf("%w: type %q (use %s or %s)", ErrInvalidConfig, cfg.Type, ProjectTypePHP, ProjectTypeProxy)
+	}
+
+	if cfg.Command == "" {
+		return fmt.Errorf("%w: proxy projects need a command", ErrInvalidConfig)
+	}
+	if err := ValidatePort(cfg.UpstreamPort); err != nil {
+		return fmt.Errorf("upstream: %w", err)
+	}
+	if cfg.UpstreamPort == port {
+		return fmt.Errorf("%w: upstream %d is the project nginx port", ErrPortConflict, cfg.UpstreamPort)
+	}
+	if user, ok := r.ports().User(cfg.UpstreamPort, cfg.Name); ok {
+		return fmt.Errorf("%w: upstream %d is used by project %q", ErrPortConflict, cfg.UpstreamPort, user)
+	}
+	return nil
+}
@@ -4,8 +4,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
+
+	"pit/internal/events"
+	"pit/pkg/pitapi"
 )
 
+var projectNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
 type ProjectRegistry struct {
 	BasePath string
 }
@@ -22,29 +29,129 @@ func (r *ProjectRegistry) projectPath(name string) string {
 	return filepath.Join(r.BasePath, "projects", name)
 }
 
+func (r *ProjectRegistry) runtimePath(name string) string {
+	return filepath.Join(r.BasePath, "runtime", name)
+}
+
+func (r *ProjectRegistry) archivePath(name string) string {
+	stamp := time.Now().Format("20060102-150405")
+	return filepath.Join(r.BasePath, "archive", name+"-"+stamp)
+}
+
+// ValidateProjectName rejects names that are unsafe as directory,
+// pool or nginx identifiers.
+func ValidateProjectName(name string) error {
+	if !projectNameRe.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidProjectName, name)
+	}
+	return nil
+}
+
+// Exists reports whether projects/<name> is present. Invalid names
+// never exist.
+func (r *ProjectRegistry) Exists(name string) bool {
+	if ValidateProjectName(name) != nil {
+		return false
+	}
+	st, err := os.Stat(r.projectPath(name))
+	return err == nil && st.IsDir()
+}
+
 // -----------------------
 // CREATE PROJECT
 // -----------------------
 
 func (r *ProjectRegistry) Create(name string) error {
+	if err := ValidateProjectName(name); err != nil {
+		return err
+	}
+	if r.Exists(name) {
+		return fmt.Errorf("%w: %s", ErrProjectExists, name)
+	}
+	if err := r.checkDomains(name, []string{DefaultDomain(name)}); err != nil {
+		return err
+	}
+
 	root := r.projectPath(name)
 	cfgDir := filepath.Join(root, ".pit")
 
 	if err := os.MkdirAll(filepath.Join(root, "public"), 0755); err != nil {
 		return err
 	}
-	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+	// .pit/nginx/*.conf snippets are included in the project server block
+	if err := os.MkdirAll(filepath.Join(cfgDir, "nginx"), 0755); err != nil {
+		return err
+	}
+	// .pit/secrets.env stays local
+	ensureSecretsIgnored(root)
+
+	port, err := r.ports().Allocate(name)
+	if err != nil {
 		return err
 	}
 
 	cfg := &ProjectConfig{
 		Name:       name,
 		PHPVersion: "83",
-		Port:       10000,
+		Port:       port,
 		Root:       "public",
 	}
 
-	return r.SaveConfig(name, cfg)
+	if err := r.SaveConfig(name, cfg); err != nil {
+		_ = r.ports().Release(name)
+		return err
+	}
+
+	events.Publish(events.Event{Type: events.ProjectCreated, Project: name})
+	return nil
+}
+
+// -----------------------
+// DELETE PROJECT
+// -----------------------
+
+// Delete stops the project runtime, removes runtime/<name> and then
+// either removes projects/<name> or, when archive is true, moves it to
+// archive/<name>-<timestamp>. It returns the archive path (if any).
+func (r *ProjectRegistry) Delete(name string, archive bool) (string, error) {
+	if err := ValidateProjectName(name); err != nil {
+		return "", err
+	}
+	if !r.Exists(name) {
+		return "", fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
+
+	// stop runtime (best effort: config may be broken)
+	if peng, err := r.Load(name); err == nil {
+		_ = peng.Stop()
+	}
+
+	if err := os.RemoveAll(r.runtimePath(name)); err != nil {
+		return "", fmt.Errorf("failed removing runtime: %w", err)
+	}
+	defer func() { _ = r.ports().Release(name) }()
+
+	if !archive {
+		if err := os.RemoveAll(r.projectPath(name)); err != nil {
+			return "", fmt.Errorf("failed removing project: %w", err)
+		}
+		events.Publish(events.Event{Type: events.ProjectDeleted, Project: name})
+		return "", nil
+	}
+
+	dst := r.archivePath(name)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(r.projectPath(name), dst); err != nil {
+		return "", fmt.Errorf("failed archiving project: %w", err)
+	}
+	events.Publish(events.Event{
+		Type:    events.ProjectDeleted,
+		Project: name,
+		Data:    map[string]any{"archived_to": dst},
+	})
+	return dst, nil
 }
 
 // -----------------------
@@ -63,14 +170,6 @@ func (r *ProjectRegistry) SaveConfig(name string, cfg *ProjectConfig) error {
 	return SaveProjectConfig(r.BasePath, name, cfg)
 }
 
-// -----------------------
-// UPDATE CONFIG
-// -----------------------
-
-func (r *ProjectRegistry) Update(name string, cfg *ProjectConfig) error {
-	return r.SaveConfig(name, cfg)
-}
-
 // -----------------------
 // LIST PROJECTS
 // -----------------------
@@ -79,6 +178,9 @@ func (r *ProjectRegistry) List() ([]string, error) {
 	dir := filepath.Join(r.BasePath, "projects")
 
 	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -97,16 +199,221 @@ func (r *ProjectRegistry) List() ([]string, error) {
 // -----------------------
 
 func (r *ProjectRegistry) Load(name string) (*ProjectEngine, error) {
+	if err := ValidateProjectName(name); err != nil {
+		return nil, err
+	}
 	if _, err := os.Stat(r.projectPath(name)); err != nil {
-		return nil, fmt.Errorf("project not found: %s", name)
+		return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
 	}
 	return NewProjectEngine(r.BasePath, name)
 }
 
+// -----------------------
+// RESTART / UPDATE (shared by CLI + API)
+// -----------------------
+
+// Restart stops and starts the project runtime, reporting start errors.
+func (r *ProjectRegistry) Restart(name string) error {
+	peng, err := r.Load(name)
+	if err != nil {
+		return err
+	}
+
+	_ = peng.Stop()
+	return peng.Start()
+}
+
+// ProjectConfigPatch lists the fields an update may change; nil/empty
+// fields are left untouched.
+type ProjectConfigPatch = pitapi.ProjectConfigPatch
+
+// Update validates and applies patch, saves the config and restarts
+// the runtime. The saved config is returned even if the restart fails.
+func (r *ProjectRegistry) Update(name string, patch ProjectConfigPatch) (*ProjectConfig, error) {
+	cfg, err := r.ReadConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPort := cfg.Port
+	oldVersion := cfg.PHPVersion
+
+	if patch.Port != nil {
+		if err := ValidatePort(*patch.Port); err != nil {
+			return nil, err
+		}
+	}
+	if patch.PHPVersion != "" {
+		if !r.phpVersionExists(patch.PHPVersion) {
+			return nil, fmt.Errorf("%w: %s", ErrPHPVersionNotFound, patch.PHPVersion)
+		}
+		cfg.PHPVersion = patch.PHPVersion
+	}
+	if patch.Domains != nil || patch.Aliases != nil {
+		if err := r.applyDomains(name, cfg, patch); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Framework != nil {
+		fw := *patch.Framework
+		if fw == "auto" {
+			fw = ""
+		}
+		if err := ValidateFramework(r.BasePath, fw); err != nil {
+			return nil, err
+		}
+		cfg.Framework = fw
+	}
+	if patch.Root != nil {
+		root, err := r.validateRoot(name, *patch.Root)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Root = root
+	}
+	if err := r.applyProxy(cfg, patch); err != nil {
+		return nil, err
+	}
+	if patch.Env != nil || patch.EnvFiles != nil {
+		if patch.Env != nil {
+			cfg.Env = *patch.Env
+		}
+		if patch.EnvFiles != nil {
+			cfg.EnvFiles = *patch.EnvFiles
+		}
+		if err := validateEnv(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Processes != nil {
+		if err := validateProcesses(*patch.Processes); err != nil {
+			return nil, err
+		}
+		cfg.Processes = *patch.Processes
+	}
+	if patch.PHPIni != nil || patch.Pool != nil {
+		if patch.PHPIni != nil {
+			cfg.PHPIni = *patch.PHPIni
+		}
+		if patch.Pool != nil {
+			cfg.Pool = *patch.Pool
+		}
+		if _, err := validatePHPSettings(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Extensions != nil || (patch.PHPVersion != "" && len(cfg.Extensions) > 0) {
+		if patch.Extensions != nil {
+			cfg.Extensions = *patch.Extensions
+		}
+		if _, err := validateExtensions(r.BasePath, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Debug != nil {
+		if err := r.applyDebug(cfg, *patch.Debug); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Cron != nil {
+		if err := validateCron(*patch.Cron); err != nil {
+			return nil, err
+		}
+		cfg.Cron = *patch.Cron
+	}
+
+	// claim the new port last: everything else has been validated
+	if patch.Port != nil && *patch.Port != oldPort {
+		if err := r.ports().Reserve(name, *patch.Port); err != nil {
+			return nil, err
+		}
+		cfg.Port = *patch.Port
+	}
+
+	// the pool lives under the PHP version: stop it with the config it
+	// was started from, or php/<old>/…/pit_<name>.conf stays behind and
+	// keeps the old master serving the project's socket
+	stopped := false
+	if cfg.PHPVersion != oldVersion {
+		if peng, err := r.Load(name); err == nil {
+			_ = peng.Stop()
+			stopped = true
+		}
+	}
+
+	if err := r.SaveConfig(name, cfg); err != nil {
+		if cfg.Port != oldPort {
+			_ = r.ports().Reserve(name, oldPort)
+		}
+		if stopped {
+			_ = r.Restart(name)
+		}
+		return nil, err
+	}
+
+	events.Publish(events.Event{
+		Type:    events.ProjectConfigUpdated,
+		Project: name,
+		Data: map[string]any{
+			"port":        cfg.Port,
+			"php_version": cfg.PHPVersion,
+			"hostnames":   cfg.Hostnames(),
+			"type":        cfg.Type,
+		},
+	})
+
+	if err := r.Restart(name); err != nil {
+		return cfg, fmt.Errorf("config saved but restart failed: %w", err)
+	}
+	return cfg, nil
+}
+
+func (r *ProjectRegistry) applyDomains(name string, cfg *ProjectConfig, patch ProjectConfigPatch) error {
+	if patch.Domains != nil {
+		domains, err := normalizeHosts(*patch.Domains, false)
+		if err != nil {
+			return err
+		}
+		cfg.Domains = domains
+	}
+	if patch.Aliases != nil {
+		aliases, err := normalizeHosts(*patch.Aliases, true)
+		if err != nil {
+			return err
+		}
+		cfg.Aliases = aliases
+	}
+	return r.checkDomains(name, cfg.Hostnames())
+}
+
+func (r *ProjectRegistry) ports() *PortAllocator {
+	return NewPortAllocator(r.BasePath)
+}
+
+func (r *ProjectRegistry) phpVersionExists(ver string) bool {
+	st, err := os.Stat(filepath.Join(r.BasePath, "php", ver))
+	return err == nil && st.IsDir()
+}
+
+// ValidatePort accepts unprivileged TCP ports only (project nginx does
+// not get cap_net_bind_service).
+func ValidatePort(port int) error {
+	if port < 1024 || port > 65535 {
+		return fmt.Errorf("%w: %d (must be 1024-65535)", ErrInvalidPort, port)
+	}
+	return nil
+}
+
 // -----------------------
 // READ CONFIG (API)
 // -----------------------
 
 func (r *ProjectRegistry) ReadConfig(name string) (*ProjectConfig, error) {
+	if err := ValidateProjectName(name); err != nil {
+		return nil, err
+	}
+	if !r.Exists(name) {
+		return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
 	return r.LoadConfig(name)
 }
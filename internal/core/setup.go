@@ -5,6 +5,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+
+	"pit/internal/certs"
 )
 
 func hasCap(bin string) bool {
@@ -46,6 +48,29 @@ func (e *Engine) SetupTrust() error {
 		}
 	}
 
+	// ----------------------------
+	// LOCAL CA (HTTPS FOR *.test)
+	// ----------------------------
+	ca := certs.New(e.BasePath)
+	created, err := ca.EnsureCA()
+	if err != nil {
+		return fmt.Errorf("creating local CA: %w", err)
+	}
+	if created {
+		fmt.Println("[Trust] Created local CA:", ca.CAPath())
+	}
+	if created || !ca.Installed() {
+		if err := ca.InstallTrust(); err != nil {
+			// HTTPS still works, the browser just warns until imported
+			fmt.Println("[Trust] Could not install local CA:", err)
+		}
+	} else {
+		fmt.Println("[Trust] Local CA already trusted")
+	}
+	if fp, err := ca.Fingerprint(); err == nil {
+		fmt.Println("[Trust] CA SHA-256:", fp)
+	}
+
 	// ----------------------------
 	// MYSQL ROOT AUTH
 	// ----------------------------
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pit/internal/services"
+	"pit/pkg/pitapi"
+)
+
+// -----------------------------------------------------------
+// XDEBUG PER PROJECT
+// -----------------------------------------------------------
+//
+// Xdebug is a zend_extension and can only be loaded by an FPM master,
+// so a project with a debug block gets its own master (see
+// ProjectPHPService.Standalone) that loads Xdebug while debugging is
+// on. Turning it on or off restarts that master only.
+
+type DebugConfig = pitapi.DebugConfig
+
+const (
+	defaultXdebugMode = "debug"
+	firstXdebugPort   = 9003 // Xdebug 3 default
+)
+
+var xdebugModes = map[string]bool{
+	"off": true, "develop": true, "debug": true, "profile": true,
+	"coverage": true, "trace": true, "gcstats": true,
+}
+
+// FindXdebug returns the xdebug.so shipped in php/<version>/lib.
+func FindXdebug(base, version string) (string, error) {
+	lib := filepath.Join(base, "php", version, "lib")
+
+	var found string
+	_ = filepath.WalkDir(lib, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fs.SkipDir
+		}
+		if !d.IsDir() && d.Name() == "xdebug.so" {
+			found = path
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if found == "" {
+		return "", fmt.Errorf("%w: xdebug.so not found in %s", ErrExtensionNotFound, lib)
+	}
+	return found, nil
+}
+
+// applyDebug merges a debug patch into cfg: checks Xdebug is available
+// when enabling, validates the mode and picks a client port.
+func (r *ProjectRegistry) applyDebug(cfg *ProjectConfig, patch DebugConfig) error {
+	d := DebugConfig{}
+	if cfg.Debug != nil {
+		d = *cfg.Debug
+	}
+	d.Enabled = patch.Enabled
+	if patch.Mode != "" {
+		d.Mode = patch.Mode
+	}
+	if patch.ClientPort != 0 {
+		d.ClientPort = patch.ClientPort
+	}
+	if d.Mode == "" {
+		d.Mode = defaultXdebugMode
+	}
+
+	for _, m := range strings.Split(d.Mode, ",") {
+		if !xdebugModes[strings.TrimSpace(m)] {
+			return fmt.Errorf("%w: xdebug mode %q (use debug, profile, coverage, develop, trace or gcstats)", ErrInvalidConfig, m)
+		}
+	}
+	if d.Enabled {
+		if _, err := FindXdebug(r.BasePath, cfg.PHPVersion); err != nil {
+			return err
+		}
+	}
+
+	if d.ClientPort == 0 {
+		d.ClientPort = r.freeXdebugPort(cfg.Name)
+	}
+	if err := ValidatePort(d.ClientPort); err != nil {
+		return fmt.Errorf("xdebug client port: %w", err)
+	}
+
+	cfg.Debug = &d
+	return nil
+}
+
+// freeXdebugPort is the first port from 9003 no other project's debug
+// block uses, so IDE listeners per project do not collide.
+func (r *ProjectRegistry) freeXdebugPort(self string) int {
+	used := map[int]bool{}
+	projects, _ := r.List()
+	for _, p := range projects {
+		if p == self {
+			continue
+		}
+		if cfg, err := LoadProjectConfig(r.BasePath, p); err == nil && cfg.Debug != nil {
+			used[cfg.Debug.ClientPort] = true
+		}
+	}
+
+	port := firstXdebugPort
+	for used[port] {
+		port++
+	}
+	return port
+}
+
+// applyXdebug moves the pool to its own master and, when debugging is
+// on, loads Xdebug there with php_admin_value[xdebug.*]. xdebug.mode
+// is only read at startup, so it goes on the master's command line.
+func applyXdebug(base string, cfg *ProjectConfig, php *services.ProjectPHPService) error {
+	php.Standalone = true
+	if !cfg.Debug.Enabled {
+		return nil
+	}
+
+	so, err := FindXdebug(base, cfg.PHPVersion)
+	if err != nil {
+		return err
+	}
+	php.ZendExtensions = append(php.ZendExtensions, so)
+
+	outDir := filepath.Join(base, "runtime", cfg.Name, "xdebug")
+	_ = os.MkdirAll(outDir, 0o755)
+
+	if php.INI == nil {
+		php.INI = map[string]string{}
+	}
+	mode := cfg.Debug.Mode
+	if v, ok := php.INI["xdebug.mode"]; ok { // php_ini wins
+		mode = v
+		delete(php.INI, "xdebug.mode")
+	}
+	php.StartupINI = map[string]string{"xdebug.mode": mode}
+
+	for k, v := range map[string]string{
+		"xdebug.client_host":        "127.0.0.1",
+		"xdebug.client_port":        fmt.Sprint(cfg.Debug.ClientPort),
+		"xdebug.start_with_request": "yes",
+		"xdebug.output_dir":         outDir,
+	} {
+		if _, ok := php.INI[k]; !ok { // php_ini wins
+			php.INI[k] = v
+		}
+	}
+
+	// PhpStorm matches requests to a server by this name
+	if _, ok := php.Env["PHP_IDE_CONFIG"]; !ok {
+		php.Env = mergeEnv(php.Env, map[string]string{
+			"PHP_IDE_CONFIG": "serverName=" + cfg.Hostnames()[0],
+		})
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// ==========================================================
+// EVENT BUS
+// ==========================================================
+//
+// In-process pub/sub for engine and project state changes. Engine,
+// ProjectEngine, the supervisor and tools.Manager publish; the API
+// streams them to clients over SSE (GET /events).
+//
+// Publish never blocks: a subscriber that falls behind by more than
+// its buffer loses events (counted in Dropped) instead of stalling the
+// engine.
+
+type Type string
+
+const (
+	ServiceStarted Type = "service.started"
+	ServiceStopped Type = "service.stopped"
+	ServiceCrashed Type = "service.crashed"
+
+	ProjectCreated       Type = "project.created"
+	ProjectDeleted       Type = "project.deleted"
+	ProjectConfigUpdated Type = "project.config_updated"
+
+	PHPVersionSwitched Type = "php.version_switched"
+	ToolsSynced        Type = "tools.synced"
+	HostsUpdated       Type = "hosts.updated"
+
+	CronFinished Type = "cron.finished"
+)
+
+type Event struct {
+	ID      uint64         `json:"id"`
+	Type    Type           `json:"type"`
+	Time    time.Time      `json:"time"`
+	Project string         `json:"project,omitempty"`
+	Service string         `json:"service,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+type Bus struct {
+	mu   sync.Mutex
+	seq  uint64
+	subs map[*Subscription]struct{}
+}
+
+// Default is the bus shared by everything in this pit process.
+var Default = NewBus()
+
+func NewBus() *Bus {
+	return &Bus{subs: map[*Subscription]struct{}{}}
+}
+
+type Subscription struct {
+	C <-chan Event
+
+	bus     *Bus
+	ch      chan Event
+	once    sync.Once
+	dropped uint64
+}
+
+// Publish stamps ev with an ID and time and fans it out.
+func (b *Bus) Publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	ev.ID = b.seq
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	for s := range b.subs {
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// Subscribe registers a listener with the given buffer size.
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, bus: b, ch: ch}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Close unregisters the subscription and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// Dropped reports how many events were lost because C was full.
+func (s *Subscription) Dropped() uint64 {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
+
+// ----------------------------------------------------------
+// DEFAULT BUS SHORTCUTS
+// ----------------------------------------------------------
+
+func Publish(ev Event) { Default.Publish(ev) }
+
+func Subscribe(buffer int) *Subscription { return Default.Subscribe(buffer) }
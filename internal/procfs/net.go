@@ -0,0 +1,194 @@
+package procfs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the TCP_LISTEN state as printed in /proc/net/tcp.
+const tcpListen = "0A"
+
+// TCPSocket is one row of /proc/net/tcp{,6}.
+type TCPSocket struct {
+	LocalPort int
+	Listening bool
+	Inode     uint64
+}
+
+// ------------------------------------
+// TCP
+// ------------------------------------
+
+// TCPSockets parses /proc/net/tcp and /proc/net/tcp6.
+func TCPSockets() ([]TCPSocket, error) {
+	var out []TCPSocket
+	var lastErr error
+	read := 0
+
+	for _, f := range []string{"tcp", "tcp6"} {
+		socks, err := parseTCP(filepath.Join(root, "net", f))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		read++
+		out = append(out, socks...)
+	}
+
+	if read == 0 {
+		return nil, lastErr
+	}
+	return out, nil
+}
+
+func parseTCP(path string) ([]TCPSocket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []TCPSocket
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+
+	for sc.Scan() {
+		// sl local_address rem_address st tx:rx tr:when retrnsmt uid timeout inode
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		local := fields[1]
+		i := strings.LastIndexByte(local, ':')
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.ParseUint(local[i+1:], 16, 16)
+		if err != nil {
+			continue
+		}
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+
+		out = append(out, TCPSocket{
+			LocalPort: int(port),
+			Listening: fields[3] == tcpListen,
+			Inode:     inode,
+		})
+	}
+	return out, sc.Err()
+}
+
+// PortInUse reports whether anything listens on the TCP port.
+func PortInUse(port int) bool {
+	socks, err := TCPSockets()
+	if err != nil {
+		return false
+	}
+	for _, s := range socks {
+		if s.Listening && s.LocalPort == port {
+			return true
+		}
+	}
+	return false
+}
+
+// PortOwners returns the pids holding a listening socket on port.
+// Sockets of processes owned by other users cannot be resolved and are
+// silently skipped.
+func PortOwners(port int) []int {
+	socks, err := TCPSockets()
+	if err != nil {
+		return nil
+	}
+
+	inodes := map[uint64]struct{}{}
+	for _, s := range socks {
+		if s.Listening && s.LocalPort == port && s.Inode != 0 {
+			inodes[s.Inode] = struct{}{}
+		}
+	}
+	return pidsForInodes(inodes)
+}
+
+// ------------------------------------
+// UNIX SOCKETS
+// ------------------------------------
+
+// UnixSocketOwners returns the pids holding the UNIX socket bound at path.
+func UnixSocketOwners(path string) []int {
+	f, err := os.Open(filepath.Join(root, "net", "unix"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	path = filepath.Clean(path)
+	inodes := map[uint64]struct{}{}
+
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+
+	for sc.Scan() {
+		// Num RefCount Protocol Flags Type St Inode Path
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 8 || fields[7] != path {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[6], 10, 64)
+		if err == nil && inode != 0 {
+			inodes[inode] = struct{}{}
+		}
+	}
+	return pidsForInodes(inodes)
+}
+
+// ------------------------------------
+// FD → PID MAPPING
+// ------------------------------------
+
+func pidsForInodes(inodes map[uint64]struct{}) []int {
+	if len(inodes) == 0 {
+		return nil
+	}
+
+	pids, err := PIDs()
+	if err != nil {
+		return nil
+	}
+
+	var out []int
+	for _, pid := range pids {
+		for inode := range socketInodes(pid) {
+			if _, ok := inodes[inode]; ok {
+				out = append(out, pid)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func socketInodes(pid int) map[uint64]struct{} {
+	fdDir := filepath.Join(root, strconv.Itoa(pid), "fd")
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil
+	}
+
+	out := map[uint64]struct{}{}
+	for _, e := range entries {
+		link, err := os.Readlink(filepath.Join(fdDir, e.Name()))
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(link[len("socket:["):], "]"), 10, 64)
+		if err == nil {
+			out[inode] = struct{}{}
+		}
+	}
+	return out
+}
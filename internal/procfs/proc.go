@@ -0,0 +1,303 @@
+// Package procfs inspects processes, TCP ports and UNIX sockets by
+// reading /proc directly (Linux only), so pit does not depend on lsof,
+// ps, ss or pgrep being installed.
+package procfs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const root = "/proc"
+
+// ------------------------------------
+// PROCESS LISTING
+// ------------------------------------
+
+// PIDs returns every process id currently visible in /proc.
+func PIDs() ([]int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		out = append(out, pid)
+	}
+	return out, nil
+}
+
+// Alive reports whether pid exists (signal 0 probe).
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// Cmdline returns the argv of pid. Programs that rewrite their process
+// title (nginx, php-fpm) end up as a single space-separated string;
+// those are split on whitespace so callers always see tokens.
+func Cmdline(pid int) ([]string, error) {
+	raw, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	raw = []byte(strings.TrimRight(string(raw), "\x00"))
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	args := strings.Split(string(raw), "\x00")
+	if len(args) == 1 {
+		return strings.Fields(args[0]), nil
+	}
+	return args, nil
+}
+
+// Title returns the raw process title (argv joined by spaces).
+func Title(pid int) string {
+	args, err := Cmdline(pid)
+	if err != nil {
+		return ""
+	}
+	return strings.Join(args, " ")
+}
+
+// Comm returns the kernel command name of pid.
+func Comm(pid int) string {
+	raw, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// Exe resolves /proc/<pid>/exe. It fails for processes owned by other
+// users.
+func Exe(pid int) (string, error) {
+	return os.Readlink(filepath.Join(root, strconv.Itoa(pid), "exe"))
+}
+
+// Cwd resolves /proc/<pid>/cwd. It fails for processes owned by other
+// users.
+func Cwd(pid int) (string, error) {
+	cwd, err := os.Readlink(filepath.Join(root, strconv.Itoa(pid), "cwd"))
+	return strings.TrimSuffix(cwd, " (deleted)"), err
+}
+
+// PPid returns the parent pid of pid.
+func PPid(pid int) int {
+	st, err := readStat(pid)
+	if err != nil || len(st) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(st[1])
+	return ppid
+}
+
+// Children returns the direct children of pid.
+func Children(pid int) []int {
+	pids, err := PIDs()
+	if err != nil {
+		return nil
+	}
+
+	var out []int
+	for _, p := range pids {
+		if PPid(p) == pid {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// readStat returns the fields of /proc/<pid>/stat after the command
+// name, i.e. index 0 is the state, 1 is ppid, ...
+func readStat(pid int) ([]string, error) {
+	raw, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(raw)
+	// comm may contain spaces and parens; it ends at the last ')'
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 || i+2 > len(s) {
+		return nil, os.ErrInvalid
+	}
+	return strings.Fields(s[i+2:]), nil
+}
+
+// ------------------------------------
+// MATCHING
+// ------------------------------------
+
+// Find returns every pid whose argv satisfies match.
+func Find(match func(pid int, args []string) bool) []int {
+	pids, err := PIDs()
+	if err != nil {
+		return nil
+	}
+
+	self := os.Getpid()
+
+	var out []int
+	for _, pid := range pids {
+		if pid == self {
+			continue
+		}
+		args, err := Cmdline(pid)
+		if err != nil || len(args) == 0 {
+			continue
+		}
+		if match(pid, args) {
+			out = append(out, pid)
+		}
+	}
+	return out
+}
+
+// ProcessRunning reports whether a process with the given program name
+// exists (matched against comm and the argv[0] base name).
+func ProcessRunning(name string) bool {
+	found := Find(func(pid int, args []string) bool {
+		return Comm(pid) == name || filepath.Base(args[0]) == name ||
+			strings.TrimSuffix(args[0], ":") == name
+	})
+	return len(found) > 0
+}
+
+// OwnedBy reports whether pid runs a binary located under dir. This is
+// the ownership check used before pit signals anything it did not start
+// itself.
+func OwnedBy(pid int, dir string) bool {
+	dir = filepath.Clean(dir) + string(os.PathSeparator)
+
+	if exe, err := Exe(pid); err == nil {
+		exe = strings.TrimSuffix(exe, " (deleted)")
+		return strings.HasPrefix(exe, dir)
+	}
+
+	// exe unreadable: nginx/php-fpm masters keep the binary path in
+	// their title, e.g. "nginx: master process /base/nginx/sbin/nginx ..."
+	// or "php-fpm: master process (/base/php/83/etc/php-fpm.conf)"
+	for _, a := range argsOrNil(pid) {
+		a = strings.Trim(a, "()")
+		if filepath.IsAbs(a) && strings.HasPrefix(a, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunsIn reports whether pid's working directory is dir or below it;
+// the ownership check for user commands (sh -c ...), whose binary is
+// not pit's.
+func RunsIn(pid int, dir string) bool {
+	cwd, err := Cwd(pid)
+	if err != nil {
+		return false
+	}
+	dir = filepath.Clean(dir)
+	return cwd == dir || strings.HasPrefix(cwd, dir+string(os.PathSeparator))
+}
+
+func argsOrNil(pid int) []string {
+	args, _ := Cmdline(pid)
+	return args
+}
+
+// ArgValue returns the value following flag in args ("-p /x" → "/x").
+func ArgValue(args []string, flag string) string {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == flag {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// FindByArg returns every pid whose argv contains flag followed by
+// exactly value (e.g. nginx masters started with "-p <prefix>").
+func FindByArg(flag, value string) []int {
+	return Find(func(_ int, args []string) bool {
+		return ArgValue(args, flag) == value
+	})
+}
+
+// WithChildren returns pids followed by their direct children.
+func WithChildren(pids []int) []int {
+	out := append([]int{}, pids...)
+	for _, pid := range pids {
+		out = append(out, Children(pid)...)
+	}
+	return out
+}
+
+// Exited reports whether pid is gone or only left as a zombie waiting
+// to be reaped by its parent.
+func Exited(pid int) bool {
+	if !Alive(pid) {
+		return true
+	}
+	st, err := readStat(pid)
+	if err != nil || len(st) == 0 {
+		return true
+	}
+	return st[0] == "Z" || st[0] == "X"
+}
+
+// clock ticks per second used by /proc/<pid>/stat (USER_HZ, 100 on
+// every mainstream Linux build)
+const userHZ = 100
+
+// StartTime returns when pid was started.
+func StartTime(pid int) (time.Time, error) {
+	st, err := readStat(pid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// field 22 of stat; readStat drops the first two (pid, comm)
+	if len(st) < 20 {
+		return time.Time{}, os.ErrInvalid
+	}
+	ticks, err := strconv.ParseInt(st[19], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	boot, err := bootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return boot.Add(time.Duration(ticks) * time.Second / userHZ), nil
+}
+
+func bootTime() (time.Time, error) {
+	raw, err := os.ReadFile(filepath.Join(root, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			sec, err := strconv.ParseInt(strings.TrimSpace(line[len("btime "):]), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	return time.Time{}, os.ErrNotExist
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"pit/internal/procfs"
+	util "pit/internal/utils"
+)
+
+// ==========================================================
+// PROJECT COMMAND SERVICE (proxy backends)
+// ==========================================================
+//
+// Runs a user command (`npm run dev`, `go run .`, `uvicorn app:app`)
+// under the supervisor with PORT/HOST set to the project's upstream.
+// Output goes to runtime/<project>/logs/<Label>.log, the pid to
+// runtime/<project>/run/<Label>.pid.
+
+type CommandService struct {
+	BasePath string
+	Project  string
+	Label    string // service name suffix and log file name
+	Command  string // run with sh -c
+	Dir      string // working directory (absolute)
+	Env      map[string]string
+	Path     []string // directories put in front of PATH
+
+	// upstream the command listens on (health check + PORT); 0 = none
+	Port int
+
+	Policy     RestartPolicy
+	Supervisor *Supervisor
+
+	logFile *os.File // current run's log, closed on exit
+}
+
+func NewCommandService(base, project, label, command, dir string) *CommandService {
+	return &CommandService{
+		BasePath:   base,
+		Project:    project,
+		Label:      label,
+		Command:    command,
+		Dir:        dir,
+		Policy:     RestartOnFailure,
+		Supervisor: DefaultSupervisor,
+	}
+}
+
+func (s *CommandService) Name() string {
+	return s.Label + ":" + s.Project
+}
+
+func (s *CommandService) LogPath() string {
+	return filepath.Join(s.BasePath, "runtime", s.Project, "logs", s.Label+".log")
+}
+
+func (s *CommandService) PIDFile() string {
+	return CommandPIDFile(s.BasePath, s.Project, s.Label)
+}
+
+func (s *CommandService) projectDir() string {
+	return filepath.Join(s.BasePath, "projects", s.Project)
+}
+
+func (s *CommandService) Start() error {
+	if s.Command == "" {
+		return fmt.Errorf("%s: no command configured", s.Name())
+	}
+
+	// replace any running instance, ours or another pit's
+	_ = s.Stop()
+
+	if err := os.MkdirAll(filepath.Dir(s.LogPath()), 0o755); err != nil {
+		return err
+	}
+
+	_, err := s.Supervisor.Start(ProcessSpec{
+		Name:      s.Name(),
+		Project:   s.Project,
+		Policy:    s.Policy,
+		PIDFile:   s.PIDFile(),
+		KillGroup: true,
+		Command: func() *exec.Cmd {
+			cmd := exec.Command("sh", "-c", s.Command)
+			cmd.Dir = s.Dir
+			cmd.Env = s.environ()
+
+			// reopened on every (re)start so rotated logs are picked up
+			if f, err := os.OpenFile(s.LogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err == nil {
+				cmd.Stdout = f
+				cmd.Stderr = f
+				s.logFile = f
+			}
+			return cmd
+		},
+		OnExit: func(ExitInfo) {
+			if s.logFile != nil {
+				_ = s.logFile.Close()
+				s.logFile = nil
+			}
+		},
+	})
+	return err
+}
+
+// environ is pit's environment plus PATH additions, PORT/HOST and the
+// configured env (which wins).
+func (s *CommandService) environ() []string {
+	env := os.Environ()
+	if len(s.Path) > 0 {
+		sep := string(os.PathListSeparator)
+		env = append(env, "PATH="+strings.Join(s.Path, sep)+sep+os.Getenv("PATH"))
+	}
+	if s.Port > 0 {
+		env = append(env, "PORT="+strconv.Itoa(s.Port), "HOST=127.0.0.1")
+	}
+
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+s.Env[k])
+	}
+	return env
+}
+
+// Stop stops the supervised command or, when another pit process
+// started it (CLI without daemon), the process group in its pid file.
+func (s *CommandService) Stop() error {
+	if _, ok := s.Supervisor.Get(s.Name()); ok {
+		return s.Supervisor.Stop(s.Name())
+	}
+	if res := StopCommandPID(s.BasePath, s.Project, s.Label); res.Step != util.StopNotRunning {
+		fmt.Printf("[Stop] %s: %s\n", s.Name(), res)
+	}
+	return nil
+}
+
+func (s *CommandService) Status() ServiceStatus {
+	st := ServiceStatus{Port: s.Port}
+	if p, ok := s.Supervisor.Get(s.Name()); ok {
+		st = p.Status()
+		st.Port = s.Port
+	} else if pid := util.GetPID(s.PIDFile()); pid > 0 && !procfs.Exited(pid) && procfs.RunsIn(pid, s.projectDir()) {
+		st.Running, st.PID = true, pid
+	}
+	if s.Port > 0 {
+		return withHealth(st, "tcp", fmt.Sprintf("127.0.0.1:%d", s.Port))
+	}
+	return withHealth(st, "", "")
+}
+
+// ----------------------------------------------------------
+// COMMANDS STARTED BY ANOTHER PIT PROCESS
+// ----------------------------------------------------------
+
+// CommandPIDFile is where a project command's leader pid is kept while
+// it runs.
+func CommandPIDFile(base, project, label string) string {
+	return filepath.Join(base, "runtime", project, "run", label+".pid")
+}
+
+// StopCommandPID stops the process group recorded in the command's pid
+// file. A pid whose working directory is not inside projects/<project>
+// was recycled and is left alone.
+func StopCommandPID(base, project, label string) util.StopResult {
+	pidFile := CommandPIDFile(base, project, label)
+	defer os.Remove(pidFile)
+
+	pid := util.GetPID(pidFile)
+	if pid <= 0 || procfs.Exited(pid) || !procfs.RunsIn(pid, filepath.Join(base, "projects", project)) {
+		return util.StopResult{PID: pid, Step: util.StopNotRunning}
+	}
+
+	// started with Setpgid: the leader's pid is the group id
+	_ = syscall.Kill(-pid, syscall.SIGTERM)
+	res, _ := util.StopProcess(pid, syscall.SIGTERM, 0, nil)
+	if res.Step == util.StopNotRunning {
+		res.Step = util.StopGraceful // the group signal already did it
+	}
+	_ = syscall.Kill(-pid, syscall.SIGKILL)
+	return res
+}
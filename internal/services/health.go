@@ -0,0 +1,47 @@
+package services
+
+import (
+	"net"
+	"time"
+
+	"pit/internal/procfs"
+)
+
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+	HealthStopped   = "stopped"
+)
+
+const healthProbeTimeout = 300 * time.Millisecond
+
+// withHealth fills Uptime (from /proc when the supervisor did not) and
+// Health by connecting to the service's listen address. An empty addr
+// means "running is healthy enough".
+func withHealth(st ServiceStatus, network, addr string) ServiceStatus {
+	if !st.Running {
+		st.Health = HealthStopped
+		return st
+	}
+
+	if st.Uptime == 0 && st.PID > 0 {
+		if started, err := procfs.StartTime(st.PID); err == nil {
+			st.Uptime = int64(time.Since(started).Seconds())
+		}
+	}
+
+	if addr == "" {
+		st.Health = HealthHealthy
+		return st
+	}
+
+	conn, err := net.DialTimeout(network, addr, healthProbeTimeout)
+	if err != nil {
+		st.Health = HealthUnhealthy
+		return st
+	}
+	_ = conn.Close()
+
+	st.Health = HealthHealthy
+	return st
+}
@@ -1,10 +1,9 @@
 package services
 
-type ServiceStatus struct {
-	Running bool `json:"running"`
-	PID     int  `json:"pid"`
-	Port    int  `json:"port"`
-}
+import "pit/pkg/pitapi"
+
+// ServiceStatus is part of the public API (pkg/pitapi).
+type ServiceStatus = pitapi.ServiceStatus
 
 type Service interface {
 	Name() string
@@ -1,26 +1,45 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"syscall"
-	"time"
 
+	"pit/internal/certs"
+	"pit/internal/templates"
 	util "pit/internal/utils"
 )
 
 type NginxService struct {
-	Base    string
-	WWWRoot string
+	Base       string
+	WWWRoot    string
+	Policy     RestartPolicy
+	Supervisor *Supervisor
+
+	// optional: project hostnames to reverse-proxy to their runtimes,
+	// asked on every config generation (start / reload)
+	Routes func() []ProjectRoute
+
+	// optional: local CA; every vhost also listens on 443 once it exists
+	Certs *certs.Authority
+}
+
+// ProjectRoute sends Hostnames to the project nginx on 127.0.0.1:Port.
+type ProjectRoute struct {
+	Project   string
+	Hostnames []string
+	Port      int
 }
 
 func NewNginxService(root string, www string) *NginxService {
 	return &NginxService{
-		Base:    filepath.Join(root, "nginx"),
-		WWWRoot: www,
+		Base:       filepath.Join(root, "nginx"),
+		WWWRoot:    www,
+		Policy:     RestartAlways,
+		Supervisor: DefaultSupervisor,
 	}
 }
 
@@ -28,66 +47,87 @@ func (s *NginxService) Name() string { return "nginx" }
 
 func (s *NginxService) Start() error {
 	// Bersihkan port dan PID lama
-	util.KillPort(80)
+	util.KillPort(filepath.Dir(s.Base), 80)
 	util.CleanupPID(filepath.Join(s.Base, "logs/nginx.pid"))
 	util.PrepareNginxDirs(s.Base)
 
 	// Generate nginx.conf dinamis
 	confFile := filepath.Join(s.Base, "conf/nginx.conf")
 	if err := s.generateConfig(confFile); err != nil {
-		return err
+		// a bad www site / project / template must not take every
+		// other site down: start with what nginx accepted last time
+		var cfgErr *NginxConfigError
+		if !errors.As(err, &cfgErr) {
+			return err
+		}
+		fmt.Println("[Nginx]", err)
+		if ferr := FallbackNginxConfig(s.Base, s.Base, confFile); ferr != nil {
+			return err
+		}
+		fmt.Println("[Nginx] Starting with the last known good config")
 	}
 
 	nginxBin := filepath.Join(s.Base, "sbin/nginx")
 
 	fmt.Println("Starting Nginx global (www mode)...")
 
-	cmd := exec.Command(nginxBin,
-		"-p", s.Base,
-		"-c", confFile,
-		"-g", "daemon off;",
-	)
-
-	// Fix lib dependency untuk portable build
-	cmd.Env = append(os.Environ(),
-		"LD_LIBRARY_PATH="+filepath.Join(s.Base, "libs")+":"+os.Getenv("LD_LIBRARY_PATH"),
-	)
-
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err := s.Supervisor.Start(ProcessSpec{
+		Name:       s.Name(),
+		Policy:     s.Policy,
+		StopSignal: util.GracefulSignal("nginx"),
+		Command: func() *exec.Cmd {
+			cmd := exec.Command(nginxBin,
+				"-p", s.Base,
+				"-c", confFile,
+				"-g", "daemon off;",
+			)
+
+			// Fix lib dependency untuk portable build
+			cmd.Env = append(os.Environ(),
+				"LD_LIBRARY_PATH="+filepath.Join(s.Base, "libs")+":"+os.Getenv("LD_LIBRARY_PATH"),
+			)
+
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			return cmd
+		},
+	})
+	return err
 }
 
 func (s *NginxService) Stop() error {
 	pidFile := filepath.Join(s.Base, "logs/nginx.pid")
 
-	pid := util.GetPID(pidFile)
-	if pid <= 0 {
-		return nil
-	}
-
-	// SIGTERM = graceful shutdown
-	proc, err := os.FindProcess(pid)
-	if err != nil {
+	if _, ok := s.Supervisor.Get(s.Name()); ok {
+		err := s.Supervisor.Stop(s.Name())
+		util.CleanupPID(pidFile)
 		return err
 	}
 
-	_ = proc.Signal(syscall.SIGTERM)
-
-	// optional: wait & cleanup
-	time.Sleep(500 * time.Millisecond)
-	util.CleanupPID(pidFile)
+	// not started by this process: fall back to the pid file
+	if util.GetPID(pidFile) <= 0 {
+		return nil
+	}
 
+	// SIGQUIT = graceful shutdown (finish in-flight requests)
+	res := util.StopPID(pidFile, util.GracefulSignal("nginx"))
+	fmt.Printf("[Stop] %s: %s\n", s.Name(), res)
 	return nil
 }
 
 func (s *NginxService) Status() ServiceStatus {
+	if p, ok := s.Supervisor.Get(s.Name()); ok {
+		st := p.Status()
+		st.Port = 80
+		return withHealth(st, "tcp", "127.0.0.1:80")
+	}
+
 	pid := util.GetPID(filepath.Join(s.Base, "logs/nginx.pid"))
-	return ServiceStatus{
+	return withHealth(ServiceStatus{
 		Running: util.IsAlive(pid),
 		PID:     pid,
 		Port:    80,
-	}
+	}, "tcp", "127.0.0.1:80")
 }
 
 // -------------------------------------------------
@@ -107,42 +147,35 @@ func (s *NginxService) generateConfig(outPath string) error {
 
 	// fallback kalau www kosong
 	if serverBlocks == "" {
-		serverBlocks = fmt.Sprintf(`
-server {
-    listen 80 default_server;
-    server_name localhost;
-    root %s;
-    index index.php index.html;
-
-    location / {
-        try_files $uri $uri/ =404;
-    }
-}
-`, filepath.Join(s.Base, "html"))
+		serverBlocks, err = s.templates().Server(templates.Site{
+			Framework:   templates.Static,
+			Listen:      []string{"80 default_server"},
+			ServerNames: []string{"localhost"},
+			Root:        filepath.Join(s.Base, "html"),
+			Extra:       s.tlsDirectives([]string{"localhost"}),
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	conf := fmt.Sprintf(`
-worker_processes  1;
-
-events {
-    worker_connections  1024;
-}
-
-http {
-    include       %s;
-    default_type  application/octet-stream;
-
-    access_log  %s/access.log;
-    error_log   %s/error.log;
-
-    # pit tools
-    include %s;
+	projectBlocks, err := s.buildProjectBlocks()
+	if err != nil {
+		return err
+	}
 
-%s
-}
-`, mimeTypes, logDir, logDir, toolsInclude, serverBlocks)
+	conf, err := s.templates().Main(templates.MainConfig{
+		MimeTypes:    mimeTypes,
+		LogDir:       logDir,
+		ToolsInclude: toolsInclude,
+		Servers:      serverBlocks + projectBlocks,
+	})
+	if err != nil {
+		return err
+	}
 
-	return os.WriteFile(outPath, []byte(conf), 0644)
+	// staged + nginx -t + atomic rename
+	return InstallNginxConfig(s.Base, s.Base, outPath, []byte(conf))
 }
 
 // -------------------------------------------------
@@ -167,13 +200,10 @@ func (s *NginxService) buildServerBlocks(fastcgiConf string) (string, error) {
 		}
 
 		name := e.Name()
-		siteRoot := filepath.Join(s.WWWRoot, name)
 
-		// detect public folder (Laravel / CI4)
-		publicRoot := filepath.Join(siteRoot, "public")
-		if st, err := os.Stat(publicRoot); err == nil && st.IsDir() {
-			siteRoot = publicRoot
-		}
+		// framework from marker files (artisan, bin/console, spark,
+		// wp-config.php, index.html only) → template + document root
+		framework, siteRoot := templates.Resolve(filepath.Join(s.WWWRoot, name), "", "")
 
 		// first server becomes default server
 		listen := "80"
@@ -182,32 +212,87 @@ func (s *NginxService) buildServerBlocks(fastcgiConf string) (string, error) {
 			first = false
 		}
 
-		serverBlock := fmt.Sprintf(`
-server {
-    listen %s;
-    server_name %s.test;
-
-    root %s;
-    index index.php index.html;
+		serverBlock, err := s.templates().Server(templates.Site{
+			Framework:   framework,
+			Listen:      []string{listen},
+			ServerNames: []string{name + ".test"},
+			Root:        siteRoot,
+			FastCGIPass: "127.0.0.1:9099",
+			FastCGIConf: fastcgiConf,
+			Extra:       s.tlsDirectives([]string{name + ".test"}),
+		})
+		if err != nil {
+			return "", fmt.Errorf("www/%s: %w", name, err)
+		}
 
-    location / {
-        try_files $uri $uri/ /index.php?$query_string;
-    }
+		servers = append(servers, serverBlock)
+	}
 
-    location ~ \.php$ {
-        include %s;
-        fastcgi_pass 127.0.0.1:9099;
-        fastcgi_param SCRIPT_FILENAME $document_root$fastcgi_script_name;
-    }
+	return strings.Join(servers, "\n"), nil
 }
-`, listen, name, siteRoot, fastcgiConf)
 
-		servers = append(servers, serverBlock)
+// -------------------------------------------------
+// PROJECT PROXIES (domains / aliases → runtime/<name>)
+// -------------------------------------------------
+
+func (s *NginxService) buildProjectBlocks() (string, error) {
+	if s.Routes == nil {
+		return "", nil
 	}
 
+	var servers []string
+	for _, rt := range s.Routes() {
+		block, err := s.templates().Proxy(templates.Proxy{
+			Project:     rt.Project,
+			ServerNames: rt.Hostnames,
+			Port:        rt.Port,
+			Extra:       s.tlsDirectives(rt.Hostnames),
+		})
+		if err != nil {
+			return "", fmt.Errorf("project %s: %w", rt.Project, err)
+		}
+		servers = append(servers, block)
+	}
 	return strings.Join(servers, "\n"), nil
 }
+
+// templates reads config/templates/ overrides of the pit root.
+func (s *NginxService) templates() *templates.Set {
+	return templates.New(filepath.Dir(s.Base))
+}
+
+// -------------------------------------------------
+// HTTPS (LOCAL CA)
+// -------------------------------------------------
+
+// tlsDirectives returns the 443 listener for a server block covering
+// names, issuing (or renewing) its certificate. Empty until `pit setup`
+// has created the CA, or if issuing fails — the site stays on http.
+func (s *NginxService) tlsDirectives(names []string) string {
+	if s.Certs == nil || !s.Certs.Exists() {
+		return ""
+	}
+
+	pair, err := s.Certs.Issue(names)
+	if err != nil {
+		fmt.Printf("[Certs] %s: %v (http only)\n", names[0], err)
+		return ""
+	}
+	tls, err := s.templates().TLS(templates.TLS{Cert: pair.Cert, Key: pair.Key})
+	if err != nil {
+		fmt.Printf("[Certs] %s: %v (http only)\n", names[0], err)
+		return ""
+	}
+	return tls
+}
+
 func (s *NginxService) Reload() error {
+	// regenerate first so new www sites / tools are picked up; if nginx
+	// rejects the result the running config stays untouched
+	if err := s.generateConfig(filepath.Join(s.Base, "conf/nginx.conf")); err != nil {
+		return err
+	}
+
 	cmd := exec.Command(
 		filepath.Join(s.Base, "sbin", "nginx"),
 		"-p", s.Base,
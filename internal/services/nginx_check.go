@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"pit/pkg/pitapi"
+)
+
+// -------------------------------------------------
+// NGINX CONFIG CHECK (nginx -t) + ATOMIC INSTALL
+// -------------------------------------------------
+//
+// Generated configs are written to <file>.staging, checked with
+// `nginx -t -p <prefix> -c <staging>` and renamed over <file> only if
+// nginx accepts them. The config that was in place is kept as
+// <file>.last-good; a rejected one is left as <file>.rejected for
+// inspection.
+
+// NginxIssue is one line of `nginx -t` output (part of the public API).
+type NginxIssue = pitapi.ConfigIssue
+
+// NginxConfigError is returned when nginx rejects a generated config.
+type NginxConfigError struct {
+	Config string // the file that was being installed
+	Issues []NginxIssue
+	Output string // raw nginx -t output
+}
+
+func (e *NginxConfigError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "nginx rejected %s", e.Config)
+	for _, is := range e.Issues {
+		if is.Level == "warn" {
+			continue
+		}
+		b.WriteString("\n  ")
+		if is.File != "" {
+			fmt.Fprintf(&b, "%s:%d: ", is.File, is.Line)
+		}
+		fmt.Fprintf(&b, "[%s] %s", is.Level, is.Message)
+	}
+	return b.String()
+}
+
+// nginx: [emerg] unknown directive "foo" in /path/nginx.conf:12
+var nginxIssueRe = regexp.MustCompile(`^nginx: \[(\w+)\] (.*?)(?: in (\S+):(\d+))?$`)
+
+func parseNginxIssues(out string) []NginxIssue {
+	var issues []NginxIssue
+	for _, line := range strings.Split(out, "\n") {
+		m := nginxIssueRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		is := NginxIssue{Level: m[1], Message: m[2], File: m[3]}
+		is.Line, _ = strconv.Atoi(m[4])
+		issues = append(issues, is)
+	}
+	return issues
+}
+
+// CheckNginxConfig runs `nginx -t` on conf with the portable nginx in
+// nginxBase. Without an nginx binary there is nothing to check against
+// and the config is accepted.
+func CheckNginxConfig(nginxBase, prefix, conf string) error {
+	bin := filepath.Join(nginxBase, "sbin", "nginx")
+	if _, err := os.Stat(bin); err != nil {
+		return nil
+	}
+
+	cmd := exec.Command(bin, "-t", "-q", "-p", prefix, "-c", conf)
+	cmd.Env = append(os.Environ(),
+		"LD_LIBRARY_PATH="+filepath.Join(nginxBase, "libs")+":"+os.Getenv("LD_LIBRARY_PATH"),
+	)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return fmt.Errorf("running nginx -t: %w", err)
+	}
+	return &NginxConfigError{
+		Config: conf,
+		Issues: parseNginxIssues(string(out)),
+		Output: strings.TrimSpace(string(out)),
+	}
+}
+
+// InstallNginxConfig validates content as a complete nginx config and
+// atomically replaces target with it.
+func InstallNginxConfig(nginxBase, prefix, target string, content []byte) error {
+	return installChecked(target, content, func(staging string) error {
+		return CheckNginxConfig(nginxBase, prefix, staging)
+	})
+}
+
+// installChecked stages content, runs check on the staged file and
+// swaps it in when it passes, keeping the old file as last-good.
+func installChecked(target string, content []byte, check func(staging string) error) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	staging := target + ".staging"
+	if err := os.WriteFile(staging, content, 0o644); err != nil {
+		return err
+	}
+
+	if err := check(staging); err != nil {
+		_ = os.Rename(staging, target+".rejected")
+		var cfgErr *NginxConfigError
+		if errors.As(err, &cfgErr) {
+			// point at the file that will be inspected, not the
+			// staging name that no longer exists
+			cfgErr.Config = target
+			for i := range cfgErr.Issues {
+				if cfgErr.Issues[i].File == staging {
+					cfgErr.Issues[i].File = target + ".rejected"
+				}
+			}
+		}
+		return err
+	}
+
+	if old, err := os.ReadFile(target); err == nil && !bytes.Equal(old, content) {
+		_ = writeFileAtomic(target+".last-good", old)
+	}
+	_ = os.Remove(target + ".rejected")
+	return os.Rename(staging, target)
+}
+
+// InstallNginxServer validates content as http-level server blocks
+// (tool vhosts included by the global nginx) on their own, so one bad
+// vhost is rejected without touching the others.
+func InstallNginxServer(nginxBase, target string, content []byte) error {
+	return installChecked(target, content, func(staging string) error {
+		// wrapper lives in conf/ so relative includes (fastcgi_params)
+		// resolve like they do from the real nginx.conf
+		wrapper := filepath.Join(nginxBase, "conf", ".pit-check-"+filepath.Base(target))
+		conf := fmt.Sprintf("events {}\nhttp {\n    include mime.types;\n    include %s;\n}\n", staging)
+		if err := os.WriteFile(wrapper, []byte(conf), 0o644); err != nil {
+			return err
+		}
+		defer os.Remove(wrapper)
+
+		return CheckNginxConfig(nginxBase, nginxBase, wrapper)
+	})
+}
+
+// FallbackNginxConfig is used when a freshly generated config was
+// rejected: the installed target is kept if nginx still accepts it
+// (it normally does, rejected configs never replace it), otherwise
+// <target>.last-good is put back.
+func FallbackNginxConfig(nginxBase, prefix, target string) error {
+	if _, err := os.Stat(target); err == nil {
+		if CheckNginxConfig(nginxBase, prefix, target) == nil {
+			return nil
+		}
+	}
+
+	lastGood := target + ".last-good"
+	data, err := os.ReadFile(lastGood)
+	if err != nil {
+		return fmt.Errorf("no last known good config for %s", target)
+	}
+	if err := CheckNginxConfig(nginxBase, prefix, lastGood); err != nil {
+		return err
+	}
+	return writeFileAtomic(target, data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -6,19 +6,40 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
+
+	"pit/internal/procfs"
+	"pit/internal/templates"
+	util "pit/internal/utils"
 )
 
 type ProjectNginxService struct {
-	BasePath string
-	Project  string
-	Port     int
+	BasePath   string
+	Project    string
+	Port       int
+	Policy     RestartPolicy
+	Supervisor *Supervisor
+
+	// hostnames the global nginx proxies here (server_name)
+	ServerNames []string
+
+	// nginx template + absolute document root; empty Root means
+	// projects/<name>/public, empty Framework the generic PHP template
+	Framework string
+	Root      string
+
+	// proxy projects: forward everything to 127.0.0.1:Upstream
+	Upstream int
 }
 
 func NewProjectNginxService(base, project string, port int) *ProjectNginxService {
 	return &ProjectNginxService{
-		BasePath: base,
-		Project:  project,
-		Port:     port,
+		BasePath:   base,
+		Project:    project,
+		Port:       port,
+		Policy:     RestartAlways,
+		Supervisor: DefaultSupervisor,
 	}
 }
 
@@ -27,12 +48,22 @@ func (s *ProjectNginxService) Name() string {
 }
 
 func (s *ProjectNginxService) Start() error {
+	// replace any instance this process is already supervising
+	_ = s.Supervisor.Stop(s.Name())
+
 	runtimeRoot := filepath.Join(s.BasePath, "runtime", s.Project)
 	nginxRuntime := filepath.Join(runtimeRoot, "nginx")
 	runDir := filepath.Join(runtimeRoot, "run")
 	logDir := filepath.Join(nginxRuntime, "logs")
 
-	projectPublic := filepath.Join(s.BasePath, "projects", s.Project, "public")
+	docRoot := s.Root
+	if docRoot == "" {
+		docRoot = filepath.Join(s.BasePath, "projects", s.Project, "public")
+	}
+	framework := s.Framework
+	if framework == "" {
+		framework = templates.PHP
+	}
 
 	_ = os.MkdirAll(nginxRuntime, 0o755)
 	_ = os.MkdirAll(runDir, 0o755)
@@ -44,84 +75,109 @@ func (s *ProjectNginxService) Start() error {
 	nginxBin := filepath.Join(s.BasePath, "nginx/sbin/nginx")
 	sockPath := filepath.Join(runtimeRoot, "php", "php-fpm.sock")
 
-	conf := fmt.Sprintf(`
-	worker_processes 1;
-
-	events {
-		worker_connections 1024;
+	tpl := templates.New(s.BasePath)
+	server, err := tpl.Server(templates.Site{
+		Framework:   framework,
+		Listen:      []string{strconv.Itoa(s.Port)},
+		ServerNames: append([]string{"localhost"}, s.ServerNames...),
+		Root:        docRoot,
+		FastCGIPass: "unix:" + sockPath,
+		FastCGIConf: filepath.Join(s.BasePath, "nginx/conf/fastcgi.conf"),
+		FastCGIParams: []string{
+			"HTTPS $pit_https if_not_empty",
+		},
+		Upstream: s.Upstream,
+		// user snippets: extra locations, headers, rewrites, ...
+		Includes: []string{
+			filepath.Join(s.BasePath, "projects", s.Project, ".pit", "nginx", "*.conf"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("rendering nginx config: %w", err)
 	}
 
-	http {
-		include %s;
-		default_type application/octet-stream;
-
-		access_log %s/access.log;
-		error_log %s/error.log;
-
-		server {
-			listen %d;
-			server_name %s.local;
-
-			root %s;
-			index index.php index.html;
-
-			location / {
-				try_files $uri $uri/ /index.php?$query_string;
-			}
+	wrapper := templates.ProjectConfig{
+		MimeTypes: filepath.Join(s.BasePath, "nginx/conf/mime.types"),
+		LogDir:    logDir,
+		Server:    server,
+	}
+	if s.Upstream == 0 {
+		wrapper.Framework = framework
+	}
+	conf, err := tpl.ProjectMain(wrapper)
+	if err != nil {
+		return fmt.Errorf("rendering nginx config: %w", err)
+	}
 
-			location ~ \.php$ {
-				fastcgi_pass unix:%s;
-				include %s;
-				fastcgi_param SCRIPT_FILENAME %s$fastcgi_script_name;
-			}
-		}
+	// staged + nginx -t: a broken .pit/nginx snippet fails the start
+	// with nginx's message instead of a crash-looping process
+	if err := InstallNginxConfig(filepath.Join(s.BasePath, "nginx"), nginxRuntime, confFile, []byte(conf)); err != nil {
+		return err
 	}
-	`, filepath.Join(s.BasePath, "nginx/conf/mime.types"),
-		logDir, logDir,
-		s.Port,
-		s.Project,
-		projectPublic,
-		sockPath,
-		filepath.Join(s.BasePath, "nginx/conf/fastcgi.conf"),
-		projectPublic,
-	)
-
-	_ = os.WriteFile(confFile, []byte(conf), 0644)
-
-	cmd := exec.Command(nginxBin,
-		"-p", nginxRuntime,
-		"-c", confFile,
-		"-g", fmt.Sprintf("pid %s;", pidFile),
-	)
-
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	return cmd.Run()
+
+	_, err = s.Supervisor.Start(ProcessSpec{
+		Name:       s.Name(),
+		Project:    s.Project,
+		Policy:     s.Policy,
+		StopSignal: util.GracefulSignal("nginx"),
+		Command: func() *exec.Cmd {
+			cmd := exec.Command(nginxBin,
+				"-p", nginxRuntime,
+				"-c", confFile,
+				"-g", fmt.Sprintf("daemon off; pid %s;", pidFile),
+			)
+
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			return cmd
+		},
+	})
+	return err
 }
 
 func (s *ProjectNginxService) Stop() error {
 	pidFile := filepath.Join(s.BasePath, "runtime", s.Project, "run/nginx.pid")
 
+	if _, ok := s.Supervisor.Get(s.Name()); ok {
+		_ = s.Supervisor.Stop(s.Name())
+	}
+
 	data, err := os.ReadFile(pidFile)
 	if err == nil {
-		if pid, err := strconv.Atoi(string(data)); err == nil {
-			proc, _ := os.FindProcess(pid)
-			_ = proc.Kill()
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err == nil && procfs.OwnedBy(pid, s.BasePath) {
+			res, _ := util.StopProcess(pid, util.GracefulSignal("nginx"), 0, nil)
+			fmt.Printf("[Stop] %s: %s\n", s.Name(), res)
 		}
 		_ = os.Remove(pidFile)
 	}
 
-	killCmd := fmt.Sprintf(
-		"ps aux | grep nginx | grep '%s/runtime/%s' | awk '{print $2}' | xargs -r kill -9",
-		s.BasePath, s.Project,
-	)
-	exec.Command("bash", "-c", killCmd).Run()
+	// leftover masters for this exact prefix: graceful first, then any
+	// worker that outlived its master
+	nginxRuntime := filepath.Join(s.BasePath, "runtime", s.Project, "nginx")
+	masters := procfs.FindByArg("-p", nginxRuntime)
+	all := procfs.WithChildren(masters)
+	for _, pid := range masters {
+		if procfs.OwnedBy(pid, s.BasePath) {
+			_, _ = util.StopProcess(pid, util.GracefulSignal("nginx"), 0, nil)
+		}
+	}
+	for _, pid := range all {
+		if procfs.OwnedBy(pid, s.BasePath) && !procfs.Exited(pid) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
 
 	return nil
 }
 
 func (s *ProjectNginxService) Status() ServiceStatus {
+	if p, ok := s.Supervisor.Get(s.Name()); ok {
+		st := p.Status()
+		st.Port = s.Port
+		return withHealth(st, "tcp", fmt.Sprintf("127.0.0.1:%d", s.Port))
+	}
+
 	pidFile := filepath.Join(s.BasePath, "runtime", s.Project, "run/nginx.pid")
 
 	data, err := os.ReadFile(pidFile)
@@ -129,11 +185,11 @@ func (s *ProjectNginxService) Status() ServiceStatus {
 		return ServiceStatus{Running: false, Port: s.Port}
 	}
 
-	pid, _ := strconv.Atoi(string(data))
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
 
-	return ServiceStatus{
-		Running: true,
+	return withHealth(ServiceStatus{
+		Running: util.IsAlive(pid),
 		PID:     pid,
 		Port:    s.Port,
-	}
+	}, "tcp", fmt.Sprintf("127.0.0.1:%d", s.Port))
 }
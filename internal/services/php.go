@@ -10,14 +10,18 @@ import (
 )
 
 type PHPService struct {
-	Root    string
-	Version string
+	Root       string
+	Version    string
+	Policy     RestartPolicy
+	Supervisor *Supervisor
 }
 
 func NewPHPService(root string, version string) *PHPService {
 	return &PHPService{
-		Root:    root,
-		Version: version,
+		Root:       root,
+		Version:    version,
+		Policy:     RestartAlways,
+		Supervisor: DefaultSupervisor,
 	}
 }
 
@@ -33,7 +37,12 @@ func (s *PHPService) basePath() string {
 func (s *PHPService) Start() error {
 	base := s.basePath()
 
-	util.KillPort(9099)
+	// the engine's master takes over the pools of an on-demand one
+	if s.Version != "" {
+		_ = NewPHPMasterService(s.Root, s.Version).Stop()
+	}
+
+	util.KillPort(s.Root, 9099)
 	util.CleanupPID(filepath.Join(base, "logs/php-fpm.pid"))
 	util.PreparePHPDirs(base)
 
@@ -43,35 +52,59 @@ func (s *PHPService) Start() error {
 
 	fmt.Println("Starting PHP-FPM version", s.Version, "...")
 
-	cmd := exec.Command(fpmBin,
-		"-p", base,
-		"-y", conf,
-		"-c", ini,
-		"--daemonize",
-	)
+	_, err := s.Supervisor.Start(ProcessSpec{
+		Name:       s.Name(),
+		Policy:     s.Policy,
+		PIDFile:    filepath.Join(base, "logs/php-fpm.pid"),
+		StopSignal: util.GracefulSignal("php-fpm"),
+		Command: func() *exec.Cmd {
+			cmd := exec.Command(fpmBin,
+				"-p", base,
+				"-y", conf,
+				"-c", ini,
+				"--nodaemonize",
+			)
 
-	cmd.Env = append(os.Environ(),
-		"LD_LIBRARY_PATH="+filepath.Join(base, "libs")+":"+os.Getenv("LD_LIBRARY_PATH"),
-	)
+			cmd.Env = append(os.Environ(),
+				"LD_LIBRARY_PATH="+filepath.Join(base, "libs")+":"+os.Getenv("LD_LIBRARY_PATH"),
+				"PHP_INI_SCAN_DIR="+PHPScanDir(base),
+			)
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			return cmd
+		},
+	})
+	return err
 }
 
 func (s *PHPService) Stop() error {
 	base := s.basePath()
-	util.StopPID(filepath.Join(base, "logs/php-fpm.pid"))
-	util.KillPort(9099)
+
+	if _, ok := s.Supervisor.Get(s.Name()); ok {
+		_ = s.Supervisor.Stop(s.Name())
+	} else {
+		res := util.StopPID(filepath.Join(base, "logs/php-fpm.pid"), util.GracefulSignal("php-fpm"))
+		fmt.Printf("[Stop] %s: %s\n", s.Name(), res)
+	}
+
+	util.KillPort(s.Root, 9099)
 	return nil
 }
 
 func (s *PHPService) Status() ServiceStatus {
 	base := s.basePath()
+
+	if p, ok := s.Supervisor.Get(s.Name()); ok {
+		st := p.Status()
+		st.Port = 9099
+		return withHealth(st, "tcp", "127.0.0.1:9099")
+	}
+
 	pid := util.GetPID(filepath.Join(base, "logs/php-fpm.pid"))
-	return ServiceStatus{
+	return withHealth(ServiceStatus{
 		Running: util.IsAlive(pid),
 		PID:     pid,
 		Port:    9099,
-	}
+	}, "tcp", "127.0.0.1:9099")
 }
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"pit/internal/events"
+	util "pit/internal/utils"
+)
+
+// ==========================================================
+// ON-DEMAND PHP-FPM MASTERS (one per version in use)
+// ==========================================================
+//
+// PHPService runs the master of the global version (with the www pool
+// on :9099). Project pools of any other version need a master of their
+// own version: EnsurePHPMaster starts one when the first pool of that
+// version is installed and StopUnusedPHPMaster stops it once the last
+// pool is gone. Their config lives in runtime/_php/<ver>/php-fpm.conf
+// (no www pool, so versions never fight over :9099); the pid file is
+// the version's usual logs/php-fpm.pid, so ReloadPHPFPM works for both.
+
+type PHPMasterService struct {
+	BasePath   string
+	Version    string
+	Supervisor *Supervisor
+}
+
+func NewPHPMasterService(base, version string) *PHPMasterService {
+	return &PHPMasterService{
+		BasePath:   base,
+		Version:    version,
+		Supervisor: DefaultSupervisor,
+	}
+}
+
+func (s *PHPMasterService) Name() string { return "php-fpm:" + s.Version }
+
+func (s *PHPMasterService) phpBase() string {
+	return filepath.Join(s.BasePath, "php", s.Version)
+}
+
+func (s *PHPMasterService) confPath() string {
+	return filepath.Join(s.BasePath, "runtime", "_php", s.Version, "php-fpm.conf")
+}
+
+func (s *PHPMasterService) pidFile() string {
+	return filepath.Join(s.phpBase(), "logs", "php-fpm.pid")
+}
+
+// OnDemand reports whether the version's master (if any) is one of
+// these rather than the engine's PHPService.
+func (s *PHPMasterService) OnDemand() bool {
+	_, err := os.Stat(s.confPath())
+	return err == nil
+}
+
+func (s *PHPMasterService) Start() error {
+	base := s.phpBase()
+	util.CleanupPID(s.pidFile())
+	util.PreparePHPDirs(base)
+
+	conf := fmt.Sprintf(`; generated by pit: master for the project pools of PHP %s
+[global]
+pid = %s
+error_log = %s
+daemonize = no
+include = %s
+`, s.Version, s.pidFile(), filepath.Join(base, "logs", "php-fpm.log"), filepath.Join(base, "etc", "php-fpm.d", "*.conf"))
+
+	_ = os.MkdirAll(filepath.Dir(s.confPath()), 0o755)
+	if err := os.WriteFile(s.confPath(), []byte(conf), 0o644); err != nil {
+		return fmt.Errorf("failed writing fpm conf: %w", err)
+	}
+
+	fmt.Println("Starting PHP-FPM version", s.Version, "(on demand) ...")
+
+	_, err := s.Supervisor.Start(ProcessSpec{
+		Name:       s.Name(),
+		Policy:     RestartOnFailure,
+		PIDFile:    s.pidFile(),
+		StopSignal: util.GracefulSignal("php-fpm"),
+		Command: func() *exec.Cmd {
+			cmd := exec.Command(filepath.Join(base, "sbin", "php-fpm"),
+				"-p", base,
+				"-y", s.confPath(),
+				"-c", filepath.Join(base, "etc", "php.ini"),
+				"--nodaemonize",
+			)
+			cmd.Env = append(os.Environ(),
+				"LD_LIBRARY_PATH="+filepath.Join(base, "libs")+":"+os.Getenv("LD_LIBRARY_PATH"),
+				"PHP_INI_SCAN_DIR="+PHPScanDir(base),
+			)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			return cmd
+		},
+	})
+	if err != nil {
+		_ = os.Remove(s.confPath())
+	}
+	return err
+}
+
+// Stop stops the on-demand master; the engine's master of the same
+// version is never touched.
+func (s *PHPMasterService) Stop() error {
+	if !s.OnDemand() {
+		return nil
+	}
+
+	if _, ok := s.Supervisor.Get(s.Name()); ok {
+		_ = s.Supervisor.Stop(s.Name())
+	} else if util.GetPID(s.pidFile()) > 0 {
+		res := util.StopPID(s.pidFile(), util.GracefulSignal("php-fpm"))
+		fmt.Printf("[Stop] %s: %s\n", s.Name(), res)
+	}
+	return os.Remove(s.confPath())
+}
+
+func (s *PHPMasterService) Status() ServiceStatus {
+	if p, ok := s.Supervisor.Get(s.Name()); ok {
+		return p.Status()
+	}
+	pid := util.GetPID(s.pidFile())
+	return ServiceStatus{
+		Running: util.IsAlive(pid),
+		PID:     pid,
+	}
+}
+
+// ----------------------------------------------------------
+// VERSION BOOKKEEPING
+// ----------------------------------------------------------
+
+// phpMasterRunning reports whether any master (engine or on demand)
+// serves the version.
+func phpMasterRunning(base, version string) bool {
+	return util.IsAlive(util.GetPID(filepath.Join(base, "php", version, "logs", "php-fpm.pid")))
+}
+
+// PHPPools counts the pool configs installed for the version.
+func PHPPools(base, version string) int {
+	pools, _ := filepath.Glob(filepath.Join(base, "php", version, "etc", "php-fpm.d", "*.conf"))
+	return len(pools)
+}
+
+// EnsurePHPMaster makes a master of the version load the current pool
+// configs: reloads the running one or starts one on demand.
+func EnsurePHPMaster(base, version string) error {
+	if phpMasterRunning(base, version) {
+		return ReloadPHPFPM(base, version)
+	}
+
+	m := NewPHPMasterService(base, version)
+	if err := m.Start(); err != nil {
+		return err
+	}
+	events.Publish(events.Event{Type: events.ServiceStarted, Service: m.Name()})
+	return nil
+}
+
+// StopUnusedPHPMaster stops the on-demand master of the version once
+// no pool is left (FPM refuses to reload without any). It reports
+// whether it did.
+func StopUnusedPHPMaster(base, version string) bool {
+	m := NewPHPMasterService(base, version)
+	if !m.OnDemand() || PHPPools(base, version) > 0 {
+		return false
+	}
+
+	_ = m.Stop()
+	events.Publish(events.Event{Type: events.ServiceStopped, Service: m.Name()})
+	return true
+}
+
+// PHPMasters lists the on-demand masters, running or left over from a
+// previous engine, sorted by version.
+func PHPMasters(base string) []*PHPMasterService {
+	confs, _ := filepath.Glob(filepath.Join(base, "runtime", "_php", "*", "php-fpm.conf"))
+	sort.Strings(confs)
+
+	var out []*PHPMasterService
+	for _, c := range confs {
+		out = append(out, NewPHPMasterService(base, filepath.Base(filepath.Dir(c))))
+	}
+	return out
+}
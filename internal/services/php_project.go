@@ -5,8 +5,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+
+	"pit/internal/procfs"
+	util "pit/internal/utils"
 )
 
 // ==========================================================
@@ -17,17 +23,40 @@ type ProjectPHPService struct {
 	BasePath string
 	Project  string
 	Version  string
+
+	// rendered as env[KEY] (FPM clears the master's environment)
+	Env map[string]string
+
+	// pool directives (pm, pm.max_children, ...); nil = pit defaults
+	Pool map[string]string
+	// php.ini overrides, rendered as php_value / php_admin_value
+	INI map[string]string
+
+	// Standalone runs the pool in its own supervised FPM master
+	// (runtime/<project>/php/php-fpm.conf) instead of the shared one of
+	// the version, so it can load ZendExtensions (Xdebug) and be
+	// restarted without touching other projects. StartupINI is passed
+	// to that master with -d, for directives a pool cannot set
+	// (xdebug.mode).
+	Standalone     bool
+	ZendExtensions []string
+	StartupINI     map[string]string
+	Supervisor     *Supervisor
+
+	// shared extensions (absolute .so paths) loaded for this pool only
+	Extensions []string
 }
 
 // ----------------------------------------------------------
 // CONSTRUCTOR
 // ----------------------------------------------------------
 
-func NewProjectPHPService(base, project, version string, _ int) *ProjectPHPService {
+func NewProjectPHPService(base, project, version string) *ProjectPHPService {
 	return &ProjectPHPService{
-		BasePath: base,
-		Project:  project,
-		Version:  version,
+		BasePath:   base,
+		Project:    project,
+		Version:    version,
+		Supervisor: DefaultSupervisor,
 	}
 }
 
@@ -39,8 +68,15 @@ func (s *ProjectPHPService) Name() string {
 	return "php-pool:" + s.Project
 }
 
+// PHPPoolName is the FPM pool of a project. Project names are already
+// limited to [a-zA-Z0-9_-], so they are used as is: two projects never
+// share a pool.
+func PHPPoolName(project string) string {
+	return "pit_" + project
+}
+
 func (s *ProjectPHPService) poolName() string {
-	return "pit_" + strings.ReplaceAll(s.Project, "-", "_")
+	return PHPPoolName(s.Project)
 }
 
 func (s *ProjectPHPService) phpBase() string {
@@ -51,6 +87,31 @@ func (s *ProjectPHPService) poolConfPath() string {
 	return filepath.Join(s.phpBase(), "etc", "php-fpm.d", s.poolName()+".conf")
 }
 
+// legacyPoolConfPath is where older pit versions put the pool, "-"
+// mapped to "_" (my-app → pit_my_app); empty when nothing changed.
+func (s *ProjectPHPService) legacyPoolConfPath() string {
+	legacy := "pit_" + strings.ReplaceAll(s.Project, "-", "_")
+	if legacy == s.poolName() {
+		return ""
+	}
+	return filepath.Join(s.phpBase(), "etc", "php-fpm.d", legacy+".conf")
+}
+
+// dropLegacyPool removes this project's pool config from under the old
+// name (it listens on our socket). The config of a project that really
+// is called my_app is left alone.
+func (s *ProjectPHPService) dropLegacyPool() bool {
+	path := s.legacyPoolConfPath()
+	if path == "" {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "listen = "+s.sockPath()+"\n") {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
 func (s *ProjectPHPService) sockPath() string {
 	return filepath.Join(s.BasePath, "runtime", s.Project, "php", "php-fpm.sock")
 }
@@ -59,8 +120,20 @@ func (s *ProjectPHPService) logPath() string {
 	return filepath.Join(s.BasePath, "runtime", s.Project, "logs", "php-fpm.log")
 }
 
-func (s *ProjectPHPService) globalPidFile() string {
-	return filepath.Join(s.phpBase(), "logs", "php-fpm.pid")
+// standalone master files
+func (s *ProjectPHPService) masterConfPath() string {
+	return filepath.Join(s.BasePath, "runtime", s.Project, "php", "php-fpm.conf")
+}
+
+func (s *ProjectPHPService) masterPidFile() string {
+	return filepath.Join(s.BasePath, "runtime", s.Project, "run", "php-fpm.pid")
+}
+
+// Configured reports whether the pool config is installed in the FPM
+// master (also true for a pool the project no longer wants).
+func (s *ProjectPHPService) Configured() bool {
+	_, err := os.Stat(s.poolConfPath())
+	return err == nil
 }
 
 // ----------------------------------------------------------
@@ -72,8 +145,23 @@ func (s *ProjectPHPService) Start() error {
 	// Create runtime dirs
 	_ = os.MkdirAll(filepath.Join(s.BasePath, "runtime", s.Project, "php"), 0o755)
 	_ = os.MkdirAll(filepath.Join(s.BasePath, "runtime", s.Project, "logs"), 0o755)
+	_ = os.MkdirAll(filepath.Join(s.BasePath, "runtime", s.Project, "run"), 0o755)
+
+	s.dropLegacyPool()
+	if s.Standalone {
+		return s.startStandalone()
+	}
 
-	// Pool configuration
+	if err := os.WriteFile(s.poolConfPath(), []byte(s.poolConf()), 0o644); err != nil {
+		return fmt.Errorf("failed writing pool conf: %w", err)
+	}
+
+	// Reload FPM to load new pool (or start a master for this version)
+	return EnsurePHPMaster(s.BasePath, s.Version)
+}
+
+// poolConf renders the [pit_<project>] section.
+func (s *ProjectPHPService) poolConf() string {
 	poolConf := fmt.Sprintf(`
 [%s]
 listen = %s
@@ -81,22 +169,201 @@ listen.owner = %s
 listen.group = %s
 listen.mode = 0660
 
-pm = dynamic
-pm.max_children = 5
-pm.start_servers = 2
-pm.min_spare_servers = 1
-pm.max_spare_servers = 3
-
+%s
 php_admin_value[error_log] = %s
 php_admin_flag[log_errors] = on
-`, s.poolName(), s.sockPath(), os.Getenv("USER"), os.Getenv("USER"), s.logPath())
+`, s.poolName(), s.sockPath(), os.Getenv("USER"), os.Getenv("USER"), s.poolLines(), s.logPath())
 
-	if err := os.WriteFile(s.poolConfPath(), []byte(poolConf), 0o644); err != nil {
-		return fmt.Errorf("failed writing pool conf: %w", err)
+	poolConf += s.iniLines()
+	for _, ext := range s.Extensions {
+		poolConf += fmt.Sprintf("php_admin_value[extension] = %s\n", ext)
+	}
+	poolConf += s.envLines()
+	return poolConf
+}
+
+// startStandalone supervises a php-fpm master that serves only this
+// project's pool.
+func (s *ProjectPHPService) startStandalone() error {
+	// a pool left in the shared master from before: drop its config, its
+	// workers go away with the next reload of that master
+	_ = os.Remove(s.poolConfPath())
+	_ = os.Remove(s.sockPath())
+
+	conf := fmt.Sprintf(`[global]
+pid = %s
+error_log = %s
+daemonize = no
+%s`, s.masterPidFile(), s.logPath(), s.poolConf())
+	if err := os.WriteFile(s.masterConfPath(), []byte(conf), 0o644); err != nil {
+		return fmt.Errorf("failed writing fpm conf: %w", err)
+	}
+
+	base := s.phpBase()
+	args := []string{
+		"-p", base,
+		"-y", s.masterConfPath(),
+		"-c", filepath.Join(base, "etc", "php.ini"),
+		"--nodaemonize",
+	}
+	for _, ext := range s.ZendExtensions {
+		args = append(args, "-d", "zend_extension="+ext)
+	}
+	startup := make([]string, 0, len(s.StartupINI))
+	for k := range s.StartupINI {
+		startup = append(startup, k)
+	}
+	sort.Strings(startup)
+	for _, k := range startup {
+		args = append(args, "-d", k+"="+s.StartupINI[k])
 	}
 
-	// Reload FPM to load new pool
-	return s.reloadFPM()
+	_ = s.Supervisor.Stop(s.Name())
+	_, err := s.Supervisor.Start(ProcessSpec{
+		Name:       s.Name(),
+		Project:    s.Project,
+		Policy:     RestartOnFailure,
+		PIDFile:    s.masterPidFile(),
+		StopSignal: util.GracefulSignal("php-fpm"),
+		Command: func() *exec.Cmd {
+			cmd := exec.Command(filepath.Join(base, "sbin", "php-fpm"), args...)
+			cmd.Env = append(os.Environ(),
+				"LD_LIBRARY_PATH="+filepath.Join(base, "libs")+":"+os.Getenv("LD_LIBRARY_PATH"),
+				"PHP_INI_SCAN_DIR="+PHPScanDir(base),
+			)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			return cmd
+		},
+	})
+	return err
+}
+
+// poolLines renders the pm settings ("pm" first) and the slowlog path
+// when request_slowlog_timeout is on.
+func (s *ProjectPHPService) poolLines() string {
+	pool := s.Pool
+	if len(pool) == 0 {
+		pool = map[string]string{
+			"pm":                   "dynamic",
+			"pm.max_children":      "5",
+			"pm.start_servers":     "2",
+			"pm.min_spare_servers": "1",
+			"pm.max_spare_servers": "3",
+		}
+	}
+
+	keys := make([]string, 0, len(pool))
+	for k := range pool {
+		if k != "pm" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "pm = %s\n", pool["pm"])
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s = %s\n", k, pool[k])
+	}
+	if t, ok := pool["request_slowlog_timeout"]; ok && strings.TrimLeft(t, "0") != "" {
+		fmt.Fprintf(&b, "slowlog = %s\n", filepath.Join(s.BasePath, "runtime", s.Project, "logs", "php-slow.log"))
+	}
+	return b.String()
+}
+
+// directives php_value cannot change (PHP_INI_SYSTEM / PHP_INI_PERDIR)
+var adminINI = map[string]bool{
+	"upload_max_filesize": true, "post_max_size": true, "max_input_vars": true,
+	"max_file_uploads": true, "file_uploads": true, "upload_tmp_dir": true,
+	"disable_functions": true, "disable_classes": true, "open_basedir": true,
+	"allow_url_fopen": true, "allow_url_include": true, "expose_php": true,
+	"short_open_tag": true, "output_buffering": true,
+	"auto_prepend_file": true, "auto_append_file": true,
+	"realpath_cache_size": true, "realpath_cache_ttl": true,
+}
+
+// iniLines renders INI as php_value/php_flag, or the php_admin_ forms
+// for system-level directives (and opcache.*, xdebug.*).
+func (s *ProjectPHPService) iniLines() string {
+	if len(s.INI) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(s.INI))
+	for k := range s.INI {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, k := range keys {
+		v := s.INI[k]
+
+		prefix := "php_"
+		if adminINI[k] || strings.HasPrefix(k, "opcache.") || strings.HasPrefix(k, "xdebug.") {
+			prefix = "php_admin_"
+		}
+		switch strings.ToLower(v) {
+		case "on", "off", "true", "false":
+			fmt.Fprintf(&b, "%sflag[%s] = %s\n", prefix, k, strings.ToLower(v))
+		default:
+			fmt.Fprintf(&b, "%svalue[%s] = %s\n", prefix, k, iniValue(v))
+		}
+	}
+	return b.String()
+}
+
+// envLines renders Env as pool env[] entries, sorted for stable
+// configs. Values FPM's ini parser cannot hold (newlines) are skipped.
+func (s *ProjectPHPService) envLines() string {
+	if len(s.Env) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, k := range keys {
+		v := s.Env[k]
+		if strings.ContainsAny(v, "\r\n") {
+			fmt.Printf("[PHP] %s: env %s has a newline, not passed to FPM\n", s.Project, k)
+			continue
+		}
+		fmt.Fprintf(&b, "env[%s] = %s\n", k, iniQuote(v))
+	}
+	return b.String()
+}
+
+// PHP's ini parser unescapes \\, \" and \$ inside double quotes (and
+// expands ${...} there), so these are the only characters to protect.
+var iniEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+
+// iniQuote renders v as a double-quoted ini string that parses back
+// to exactly v.
+func iniQuote(v string) string {
+	return `"` + iniEscaper.Replace(v) + `"`
+}
+
+// values safe without quotes: sizes, paths, lists and constant
+// expressions (E_ALL & ~E_DEPRECATED), which quoting would turn into
+// plain strings
+var iniBareRe = regexp.MustCompile(`^[A-Za-z0-9_./:,@%+*&|~!^()-]+( +[A-Za-z0-9_./:,@%+*&|~!^()-]+)*$`)
+
+// iniValue leaves bare-safe values as they are and quotes the rest
+// (";" would start a comment, "${" expand, "=" or a stray quote break
+// the line).
+func iniValue(v string) string {
+	if iniBareRe.MatchString(v) {
+		return v
+	}
+	return iniQuote(v)
 }
 
 // ----------------------------------------------------------
@@ -105,15 +372,29 @@ php_admin_flag[log_errors] = on
 
 func (s *ProjectPHPService) Stop() error {
 
-	// Remove pool config
-	_ = os.Remove(s.poolConfPath())
+	// own master (debug sessions): only this project is affected
+	if _, ok := s.Supervisor.Get(s.Name()); ok {
+		_ = s.Supervisor.Stop(s.Name())
+	} else if pid := util.GetPID(s.masterPidFile()); pid > 0 && procfs.OwnedBy(pid, s.BasePath) {
+		res := util.StopPID(s.masterPidFile(), util.GracefulSignal("php-fpm"))
+		fmt.Printf("[Stop] %s: %s\n", s.Name(), res)
+	}
+	_ = os.Remove(s.masterConfPath())
 
 	// Remove socket
 	_ = os.Remove(s.sockPath())
 
-	// Reload twice to flush worker processes
-	_ = s.reloadFPM()
-	_ = s.reloadFPM()
+	// pool in the shared master: remove it and reload twice to flush
+	// worker processes, or stop the version's on-demand master if this
+	// was its last pool
+	legacy := s.dropLegacyPool()
+	if s.Configured() || legacy {
+		_ = os.Remove(s.poolConfPath())
+		if !StopUnusedPHPMaster(s.BasePath, s.Version) {
+			_ = s.reloadFPM()
+			_ = s.reloadFPM()
+		}
+	}
 
 	return nil
 }
@@ -123,13 +404,17 @@ func (s *ProjectPHPService) Stop() error {
 // ----------------------------------------------------------
 
 func (s *ProjectPHPService) Status() ServiceStatus {
+	if p, ok := s.Supervisor.Get(s.Name()); ok {
+		return withHealth(p.Status(), "unix", s.sockPath())
+	}
 	if _, err := os.Stat(s.sockPath()); err == nil {
-		return ServiceStatus{
+		// pool workers belong to the shared master
+		return withHealth(ServiceStatus{
 			Running: true,
 			Port:    0,
-		}
+		}, "unix", s.sockPath())
 	}
-	return ServiceStatus{Running: false}
+	return withHealth(ServiceStatus{Running: false}, "", "")
 }
 
 // ----------------------------------------------------------
@@ -137,9 +422,15 @@ func (s *ProjectPHPService) Status() ServiceStatus {
 // ----------------------------------------------------------
 
 func (s *ProjectPHPService) reloadFPM() error {
-	data, err := os.ReadFile(s.globalPidFile())
+	return ReloadPHPFPM(s.BasePath, s.Version)
+}
+
+// ReloadPHPFPM gracefully reloads (SIGUSR2) the shared FPM master of a
+// PHP version: pools and php.ini / conf.d are re-read.
+func ReloadPHPFPM(base, version string) error {
+	data, err := os.ReadFile(filepath.Join(base, "php", version, "logs", "php-fpm.pid"))
 	if err != nil {
-		return fmt.Errorf("php-fpm not running for version %s", s.Version)
+		return fmt.Errorf("php-fpm not running for version %s", version)
 	}
 
 	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
@@ -147,6 +438,15 @@ func (s *ProjectPHPService) reloadFPM() error {
 		return err
 	}
 
-	cmd := exec.Command("kill", "-USR2", strconv.Itoa(pid))
-	return cmd.Run()
+	if !procfs.Alive(pid) {
+		return fmt.Errorf("php-fpm not running for version %s", version)
+	}
+	return syscall.Kill(pid, syscall.SIGUSR2)
+}
+
+// PHPScanDir is where pit keeps the ini fragments of a PHP build
+// (extensions enabled with `pit php ext enable`); it is passed to php
+// as PHP_INI_SCAN_DIR.
+func PHPScanDir(phpBase string) string {
+	return filepath.Join(phpBase, "etc", "conf.d")
 }
@@ -0,0 +1,466 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"pit/internal/events"
+	util "pit/internal/utils"
+)
+
+// ==========================================================
+// PROCESS SUPERVISOR
+// ==========================================================
+//
+// Supervisor owns every long-running child process started by pit.
+// Each process is launched in the foreground (no self-daemonizing),
+// waited on, and restarted with exponential backoff according to its
+// RestartPolicy.
+
+type RestartPolicy string
+
+const (
+	RestartAlways    RestartPolicy = "always"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartNever     RestartPolicy = "never"
+)
+
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+
+	// a run that lasted this long resets the backoff
+	stableRunTime = 10 * time.Second
+)
+
+// ExitInfo describes one termination of a supervised process.
+type ExitInfo struct {
+	Name     string
+	PID      int
+	Code     int
+	Reason   string
+	Restart  bool // supervisor is going to restart it
+	Restarts int
+}
+
+type ProcessSpec struct {
+	Name    string
+	Policy  RestartPolicy
+	Command func() *exec.Cmd
+
+	// optional: owning project, attached to published events
+	Project string
+
+	// optional: written after every (re)start, removed on final exit
+	PIDFile string
+
+	// graceful stop: signal (default SIGTERM) and deadline before
+	// SIGKILL (default util.StopTimeout)
+	StopSignal  syscall.Signal
+	StopTimeout time.Duration
+
+	// signal the whole process group, not just the leader: for shell
+	// commands (npm run dev → node → esbuild) whose children would
+	// otherwise outlive them and keep the port
+	KillGroup bool
+
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	MaxRestarts int // 0 = unlimited
+
+	// optional hook, called after every exit
+	OnExit func(ExitInfo)
+}
+
+type Supervisor struct {
+	mu    sync.Mutex
+	procs map[string]*Process
+}
+
+// DefaultSupervisor is shared by all services of this pit process.
+var DefaultSupervisor = NewSupervisor()
+
+func NewSupervisor() *Supervisor {
+	return &Supervisor{procs: map[string]*Process{}}
+}
+
+type Process struct {
+	spec ProcessSpec
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	pid       int
+	startedAt time.Time
+	restarts  int
+	lastExit  string
+	exitCode  int
+	running   bool
+	stopping  bool
+
+	wake   chan struct{} // interrupts backoff sleep on stop
+	exited chan struct{} // closed when the current run exits
+	done   chan struct{} // closed when supervision ends
+}
+
+// ----------------------------------------------------------
+// START
+// ----------------------------------------------------------
+
+// Start launches spec.Command under supervision. It returns once the
+// first run has been started (or failed to start).
+func (s *Supervisor) Start(spec ProcessSpec) (*Process, error) {
+	if spec.Command == nil {
+		return nil, fmt.Errorf("%s: no command", spec.Name)
+	}
+	if spec.Policy == "" {
+		spec.Policy = RestartOnFailure
+	}
+	if spec.MinBackoff <= 0 {
+		spec.MinBackoff = defaultMinBackoff
+	}
+	if spec.MaxBackoff <= 0 {
+		spec.MaxBackoff = defaultMaxBackoff
+	}
+	if spec.StopSignal == 0 {
+		spec.StopSignal = syscall.SIGTERM
+	}
+
+	s.mu.Lock()
+	if old, ok := s.procs[spec.Name]; ok && old.Alive() {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%s is already running (pid %d)", spec.Name, old.PID())
+	}
+
+	p := &Process{
+		spec: spec,
+		wake: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.procs[spec.Name] = p
+	s.mu.Unlock()
+
+	first := make(chan error, 1)
+	go p.loop(first)
+
+	if err := <-first; err != nil {
+		s.mu.Lock()
+		if s.procs[spec.Name] == p {
+			delete(s.procs, spec.Name)
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Get returns the supervised process registered under name.
+func (s *Supervisor) Get(name string) (*Process, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.procs[name]
+	return p, ok
+}
+
+// Stop ends supervision of name and terminates its process.
+func (s *Supervisor) Stop(name string) error {
+	s.mu.Lock()
+	p, ok := s.procs[name]
+	delete(s.procs, name)
+	s.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return p.Stop()
+}
+
+// StopAll terminates every supervised process.
+func (s *Supervisor) StopAll() {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.procs))
+	for n := range s.procs {
+		names = append(names, n)
+	}
+	s.mu.Unlock()
+
+	for _, n := range names {
+		_ = s.Stop(n)
+	}
+}
+
+// StopProject terminates every process registered for project,
+// including ones its current config no longer lists (a command
+// removed from config.json, a proxy project switched back to php).
+func (s *Supervisor) StopProject(project string) {
+	s.mu.Lock()
+	var names []string
+	for n, p := range s.procs {
+		if p.spec.Project == project {
+			names = append(names, n)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, n := range names {
+		_ = s.Stop(n)
+	}
+}
+
+// ----------------------------------------------------------
+// SUPERVISION LOOP
+// ----------------------------------------------------------
+
+func (p *Process) loop(first chan<- error) {
+	defer close(p.done)
+
+	backoff := p.spec.MinBackoff
+	reported := false
+
+	for {
+		cmd := p.spec.Command()
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		// own process group: terminal signals go to pit, not the child
+		cmd.SysProcAttr.Setpgid = true
+
+		exited := make(chan struct{})
+
+		p.mu.Lock()
+		if p.stopping {
+			p.mu.Unlock()
+			if !reported {
+				first <- fmt.Errorf("%s: stopped before start", p.spec.Name)
+			}
+			return
+		}
+		err := cmd.Start()
+		if err == nil {
+			p.cmd = cmd
+			p.pid = cmd.Process.Pid
+			p.startedAt = time.Now()
+			p.running = true
+			p.exited = exited
+		}
+		p.mu.Unlock()
+
+		if !reported {
+			reported = true
+			first <- err
+			if err != nil {
+				return
+			}
+		} else if err == nil {
+			events.Publish(events.Event{
+				Type:    events.ServiceStarted,
+				Project: p.spec.Project,
+				Service: p.spec.Name,
+				Message: "restarted by supervisor",
+				Data:    map[string]any{"pid": cmd.Process.Pid, "restarts": p.restarts},
+			})
+		}
+
+		var code int
+		var reason string
+		var ranFor time.Duration
+
+		if err != nil {
+			code = -1
+			reason = "start failed: " + err.Error()
+		} else {
+			p.writePIDFile()
+			waitErr := cmd.Wait()
+			ranFor = time.Since(p.startedAt)
+			code, reason = describeExit(cmd, waitErr)
+
+			if p.spec.KillGroup {
+				// leader gone: nothing of this run may survive it
+				_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			}
+		}
+
+		p.mu.Lock()
+		p.running = false
+		p.exitCode = code
+		p.lastExit = reason
+		stopping := p.stopping
+		restart := !stopping && p.shouldRestart(code)
+		if restart {
+			p.restarts++
+		}
+		info := ExitInfo{
+			Name:     p.spec.Name,
+			PID:      p.pid,
+			Code:     code,
+			Reason:   reason,
+			Restart:  restart,
+			Restarts: p.restarts,
+		}
+		p.mu.Unlock()
+		close(exited)
+
+		if !stopping {
+			// a clean exit nobody asked for is a stop, anything else a crash
+			typ := events.ServiceCrashed
+			if code == 0 {
+				typ = events.ServiceStopped
+			}
+			events.Publish(events.Event{
+				Type:    typ,
+				Project: p.spec.Project,
+				Service: p.spec.Name,
+				Message: reason,
+				Data:    map[string]any{"pid": info.PID, "code": code, "restart": restart, "restarts": info.Restarts},
+			})
+		}
+
+		if p.spec.OnExit != nil {
+			p.spec.OnExit(info)
+		}
+
+		if !restart {
+			p.removePIDFile()
+			return
+		}
+
+		if ranFor >= stableRunTime {
+			backoff = p.spec.MinBackoff
+		}
+
+		fmt.Printf("[Supervisor] %s exited (%s), restarting in %s\n", p.spec.Name, reason, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-p.wake:
+			p.removePIDFile()
+			return
+		}
+
+		backoff *= 2
+		if backoff > p.spec.MaxBackoff {
+			backoff = p.spec.MaxBackoff
+		}
+	}
+}
+
+// caller must hold p.mu
+func (p *Process) shouldRestart(code int) bool {
+	if p.spec.MaxRestarts > 0 && p.restarts >= p.spec.MaxRestarts {
+		return false
+	}
+	switch p.spec.Policy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return code != 0
+	default:
+		return false
+	}
+}
+
+func describeExit(cmd *exec.Cmd, err error) (int, string) {
+	if err == nil {
+		return 0, "exited with code 0"
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return -1, "killed by signal " + ws.Signal().String()
+		}
+		return exitErr.ExitCode(), fmt.Sprintf("exited with code %d", exitErr.ExitCode())
+	}
+
+	return -1, err.Error()
+}
+
+func (p *Process) writePIDFile() {
+	if p.spec.PIDFile == "" {
+		return
+	}
+	_ = os.MkdirAll(filepath.Dir(p.spec.PIDFile), 0o755)
+	_ = os.WriteFile(p.spec.PIDFile, []byte(fmt.Sprintf("%d", p.PID())), 0o644)
+}
+
+func (p *Process) removePIDFile() {
+	if p.spec.PIDFile != "" {
+		_ = os.Remove(p.spec.PIDFile)
+	}
+}
+
+// ----------------------------------------------------------
+// STOP
+// ----------------------------------------------------------
+
+// Stop disables restarts and terminates the current run with the
+// shared graceful stop routine (StopSignal → StopTimeout → SIGKILL).
+func (p *Process) Stop() error {
+	p.mu.Lock()
+	if p.stopping {
+		p.mu.Unlock()
+		<-p.done
+		return nil
+	}
+	p.stopping = true
+	close(p.wake)
+	pid := p.pid
+	running := p.running
+	exited := p.exited
+	p.mu.Unlock()
+
+	var err error
+	if running {
+		if p.spec.KillGroup {
+			_ = syscall.Kill(-pid, p.spec.StopSignal)
+		}
+		var res util.StopResult
+		res, err = util.StopProcess(pid, p.spec.StopSignal, p.spec.StopTimeout, exited)
+		fmt.Printf("[Stop] %s: %s\n", p.spec.Name, res)
+	}
+
+	<-p.done
+	return err
+}
+
+// ----------------------------------------------------------
+// STATUS
+// ----------------------------------------------------------
+
+func (p *Process) PID() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pid
+}
+
+// Alive reports whether a run is active or a restart is pending.
+func (p *Process) Alive() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *Process) Status() ServiceStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := ServiceStatus{
+		Running:  p.running,
+		Restarts: p.restarts,
+		LastExit: p.lastExit,
+		Policy:   string(p.spec.Policy),
+	}
+	if p.running {
+		st.PID = p.pid
+		st.Uptime = int64(time.Since(p.startedAt).Seconds())
+	}
+	return st
+}
@@ -1,20 +1,27 @@
 package services
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+
+	util "pit/internal/utils"
 )
 
 type ToolsPHPService struct {
-	BasePath string
-	PHPBin   string
+	BasePath   string
+	PHPBin     string
+	Policy     RestartPolicy
+	Supervisor *Supervisor
 }
 
 func NewToolsPHPService(base string, phpBin string) *ToolsPHPService {
 	return &ToolsPHPService{
-		BasePath: base,
-		PHPBin:   phpBin,
+		BasePath:   base,
+		PHPBin:     phpBin,
+		Policy:     RestartOnFailure,
+		Supervisor: DefaultSupervisor,
 	}
 }
 
@@ -30,41 +37,58 @@ func (s *ToolsPHPService) socketPath() string {
 	return filepath.Join(s.runtimeDir(), "php-fpm.sock")
 }
 
+func (s *ToolsPHPService) pidFile() string {
+	return filepath.Join(s.runtimeDir(), "php-fpm.pid")
+}
+
 func (s *ToolsPHPService) Start() error {
 	rt := s.runtimeDir()
 	_ = os.MkdirAll(filepath.Join(rt, "logs"), 0755)
 
 	conf := filepath.Join(rt, "php-fpm.conf")
 
-	cmd := exec.Command(
-		s.PHPBin,
-		"--fpm-config", conf,
-		"--nodaemonize",
-	)
+	_, err := s.Supervisor.Start(ProcessSpec{
+		Name:       s.Name(),
+		Policy:     s.Policy,
+		PIDFile:    s.pidFile(),
+		StopSignal: util.GracefulSignal("php-fpm"),
+		Command: func() *exec.Cmd {
+			cmd := exec.Command(
+				s.PHPBin,
+				"--fpm-config", conf,
+				"--nodaemonize",
+			)
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Start()
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			return cmd
+		},
+	})
+	return err
 }
 
 func (s *ToolsPHPService) Stop() error {
-	// MVP stop: hapus socket (PHP-FPM exit otomatis)
+	if _, ok := s.Supervisor.Get(s.Name()); ok {
+		_ = s.Supervisor.Stop(s.Name())
+	} else {
+		res := util.StopPID(s.pidFile(), util.GracefulSignal("php-fpm"))
+		fmt.Printf("[Stop] %s: %s\n", s.Name(), res)
+	}
+
 	_ = os.Remove(s.socketPath())
 	return nil
 }
 
 func (s *ToolsPHPService) Status() ServiceStatus {
-	if _, err := os.Stat(s.socketPath()); err == nil {
-		return ServiceStatus{
-			Running: true,
-			PID:     0, // PHP-FPM tools belum tracking PID (OK untuk sekarang)
-			Port:    0, // socket-based, bukan TCP
-		}
+	if p, ok := s.Supervisor.Get(s.Name()); ok {
+		// socket-based, no TCP port
+		return withHealth(p.Status(), "unix", s.socketPath())
 	}
 
-	return ServiceStatus{
-		Running: false,
-		PID:     0,
+	pid := util.GetPID(s.pidFile())
+	return withHealth(ServiceStatus{
+		Running: util.IsAlive(pid),
+		PID:     pid,
 		Port:    0,
-	}
+	}, "unix", s.socketPath())
 }
@@ -0,0 +1,94 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// -----------------------------------------------------------
+// FRAMEWORK DETECTION
+// -----------------------------------------------------------
+//
+// Used for www/ sites (global nginx) and project runtimes: the
+// framework is detected from marker files (or pinned in
+// .pit/config.json) and picks locations/<framework>.tmpl.
+
+const (
+	Laravel      = "laravel"
+	Symfony      = "symfony"
+	WordPress    = "wordpress"
+	CodeIgniter4 = "codeigniter4"
+	Static       = "static"
+	SPA          = "spa"
+	PHP          = "php" // generic front controller, the old default
+)
+
+// Detect looks for framework markers in the project directory and then
+// in its document root (a WordPress dropped into public/ still counts).
+func Detect(projectDir, docRoot string) string {
+	for _, dir := range []string{projectDir, docRoot} {
+		switch {
+		case exists(dir, "artisan"):
+			return Laravel
+		case exists(dir, "bin", "console"):
+			return Symfony
+		case exists(dir, "spark"):
+			return CodeIgniter4
+		case exists(dir, "wp-config.php"), exists(dir, "wp-load.php"):
+			return WordPress
+		}
+	}
+
+	if exists(docRoot, "index.html") && !hasPHP(docRoot) {
+		return Static
+	}
+	return PHP
+}
+
+// DefaultRoot is the document root (relative to the project) a
+// framework serves from when none is configured.
+func DefaultRoot(fw, projectDir string) string {
+	switch fw {
+	case Laravel, Symfony, CodeIgniter4:
+		return "public"
+	case SPA:
+		if exists(projectDir, "dist", "index.html") {
+			return "dist"
+		}
+	}
+	if st, err := os.Stat(filepath.Join(projectDir, "public")); err == nil && st.IsDir() {
+		return "public"
+	}
+	return "."
+}
+
+// Resolve picks the framework (pinned, or detected when empty) and the
+// absolute document root (root, or the framework default when empty)
+// of the site in dir.
+func Resolve(dir, framework, root string) (string, string) {
+	docRoot := root
+	if docRoot == "" {
+		docRoot = DefaultRoot(framework, dir)
+	}
+	if framework == "" {
+		framework = Detect(dir, filepath.Join(dir, docRoot))
+		if root == "" {
+			docRoot = DefaultRoot(framework, dir)
+		}
+	}
+	return framework, filepath.Join(dir, docRoot)
+}
+
+// -----------------------------------------------------------
+// HELPERS
+// -----------------------------------------------------------
+
+func exists(parts ...string) bool {
+	_, err := os.Stat(filepath.Join(parts...))
+	return err == nil
+}
+
+func hasPHP(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.php"))
+	return len(matches) > 0
+}
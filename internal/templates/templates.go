@@ -0,0 +1,250 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// -----------------------------------------------------------
+// NGINX TEMPLATES (embedded, overridable in config/templates/)
+// -----------------------------------------------------------
+//
+// Every nginx config pit writes comes from a text/template file in
+// files/. A file with the same relative name under
+// <base>/config/templates/ replaces the built-in one, e.g.
+//
+//	config/templates/locations/laravel.tmpl
+//	config/templates/proxy.tmpl
+//
+// New frameworks can be added by dropping locations/<name>.tmpl there.
+
+//go:embed files
+var builtin embed.FS
+
+// Set loads templates for one pit installation.
+type Set struct {
+	Base string
+}
+
+func New(base string) *Set {
+	return &Set{Base: base}
+}
+
+// OverrideDir is where user templates live.
+func (s *Set) OverrideDir() string {
+	return filepath.Join(s.Base, "config", "templates")
+}
+
+// Frameworks lists every name accepted in the `framework` pin.
+func (s *Set) Frameworks() []string {
+	seen := map[string]bool{}
+	if entries, err := fs.ReadDir(builtin, "files/locations"); err == nil {
+		for _, e := range entries {
+			seen[strings.TrimSuffix(e.Name(), ".tmpl")] = true
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(s.OverrideDir(), "locations", "*.tmpl")); matches != nil {
+		for _, m := range matches {
+			seen[strings.TrimSuffix(filepath.Base(m), ".tmpl")] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Valid reports whether fw has a locations template.
+func (s *Set) Valid(fw string) bool {
+	if fw == "" || strings.ContainsAny(fw, `/\.`) {
+		return false
+	}
+	_, err := s.read("locations/" + fw + ".tmpl")
+	return err == nil
+}
+
+// Names lists the built-in templates by relative name.
+func (s *Set) Names() []string {
+	var names []string
+	_ = fs.WalkDir(builtin, "files", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, strings.TrimPrefix(path, "files/"))
+		}
+		return nil
+	})
+	return names
+}
+
+// Overridden reports whether config/templates/ has its own name.
+func (s *Set) Overridden(name string) bool {
+	_, err := os.Stat(filepath.Join(s.OverrideDir(), filepath.FromSlash(name)))
+	return err == nil
+}
+
+// Export copies a built-in template into config/templates/ as a
+// starting point for an override. Existing overrides are kept.
+func (s *Set) Export(name string) (string, error) {
+	data, err := builtin.ReadFile("files/" + name)
+	if err != nil {
+		return "", fmt.Errorf("template %s not found", name)
+	}
+	dst := filepath.Join(s.OverrideDir(), filepath.FromSlash(name))
+	if s.Overridden(name) {
+		return dst, fmt.Errorf("%s already exists", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", err
+	}
+	return dst, os.WriteFile(dst, data, 0o644)
+}
+
+// -----------------------------------------------------------
+// DATA
+// -----------------------------------------------------------
+
+// Site is everything a server block (server.tmpl) needs.
+type Site struct {
+	Framework   string
+	Listen      []string // "80", "80 default_server", ...
+	ServerNames []string
+	Root        string
+	FastCGIPass string // "unix:/path.sock" or "127.0.0.1:9099"
+	FastCGIConf string // nginx fastcgi.conf to include
+
+	// extra "NAME value" fastcgi_param lines for every PHP location
+	FastCGIParams []string
+
+	// raw directives placed after server_name (TLS, ...)
+	Extra string
+
+	// include globs at the end of the server block (.pit/nginx/*.conf)
+	Includes []string
+
+	// set for proxy projects: upstream.tmpl replaces the framework
+	// locations and forwards to 127.0.0.1:Upstream
+	Upstream int
+}
+
+// Proxy is a global-nginx server block forwarding to a project runtime
+// (proxy.tmpl).
+type Proxy struct {
+	Project     string
+	ServerNames []string
+	Port        int
+	Extra       string
+}
+
+// MainConfig is the http{} wrapper of the global nginx (nginx.conf.tmpl).
+type MainConfig struct {
+	MimeTypes    string
+	LogDir       string
+	ToolsInclude string
+	Servers      string
+}
+
+// ProjectConfig is the http{} wrapper of a project runtime nginx
+// (project-nginx.conf.tmpl).
+type ProjectConfig struct {
+	MimeTypes string
+	LogDir    string
+	Server    string
+
+	// framework of Server (empty for proxy projects): http-level
+	// defaults the project's snippets may override
+	Framework string
+}
+
+// TLS is the certificate couple of tls.tmpl.
+type TLS struct {
+	Cert string
+	Key  string
+}
+
+// -----------------------------------------------------------
+// RENDER
+// -----------------------------------------------------------
+
+// Server renders server.tmpl with locations/<Framework>.tmpl (or
+// upstream.tmpl for proxy projects).
+func (s *Set) Server(site Site) (string, error) {
+	if site.Upstream > 0 {
+		return s.execute("server.tmpl", site, map[string]string{
+			"fastcgi":   "fastcgi.tmpl",
+			"locations": "upstream.tmpl",
+		})
+	}
+	if !s.Valid(site.Framework) {
+		return "", fmt.Errorf("unknown framework %q (known: %s)", site.Framework, strings.Join(s.Frameworks(), ", "))
+	}
+	return s.execute("server.tmpl", site, map[string]string{
+		"fastcgi":   "fastcgi.tmpl",
+		"locations": "locations/" + site.Framework + ".tmpl",
+	})
+}
+
+func (s *Set) Proxy(p Proxy) (string, error)     { return s.execute("proxy.tmpl", p, nil) }
+func (s *Set) Main(c MainConfig) (string, error) { return s.execute("nginx.conf.tmpl", c, nil) }
+func (s *Set) ProjectMain(c ProjectConfig) (string, error) {
+	return s.execute("project-nginx.conf.tmpl", c, nil)
+}
+func (s *Set) TLS(t TLS) (string, error) { return s.execute("tls.tmpl", t, nil) }
+
+// Execute renders any template by its relative name (tool.tmpl, ...).
+func (s *Set) Execute(name string, data any) (string, error) {
+	return s.execute(name, data, nil)
+}
+
+func (s *Set) execute(name string, data any, partials map[string]string) (string, error) {
+	t, err := s.parse(template.New(name).Funcs(funcs), name)
+	if err != nil {
+		return "", err
+	}
+	for as, file := range partials {
+		if _, err := s.parse(t.New(as), file); err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func (s *Set) parse(t *template.Template, file string) (*template.Template, error) {
+	src, err := s.read(file)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.Parse(src); err != nil {
+		return nil, fmt.Errorf("template %s: %w", file, err)
+	}
+	return t, nil
+}
+
+// read prefers config/templates/<file> over the embedded copy.
+func (s *Set) read(file string) (string, error) {
+	if data, err := os.ReadFile(filepath.Join(s.OverrideDir(), filepath.FromSlash(file))); err == nil {
+		return string(data), nil
+	}
+	data, err := builtin.ReadFile("files/" + file)
+	if err != nil {
+		return "", fmt.Errorf("template %s not found", file)
+	}
+	return string(data), nil
+}
+
+var funcs = template.FuncMap{
+	"join": func(s []string) string { return strings.Join(s, " ") },
+}
@@ -3,6 +3,8 @@ package tools
 import (
 	"fmt"
 	"path/filepath"
+
+	"pit/internal/events"
 )
 
 type Manager struct {
@@ -18,11 +20,17 @@ func (m *Manager) SyncAll() error {
 	}
 
 	// 1) hosts
-	if err := SyncHosts(DomainsFromManifests(manifests)); err != nil {
+	domains := DomainsFromManifests(manifests)
+	if err := SyncHosts(domains); err != nil {
 		return ExplainHostsPermHint(err)
 	}
+	events.Publish(events.Event{
+		Type: events.HostsUpdated,
+		Data: map[string]any{"domains": domains},
+	})
 
-	// 2) vhosts
+	// 2) vhosts — a tool nginx rejects is skipped, the others go on
+	skipped := map[string]string{}
 	for _, t := range manifests {
 		if t.Type != "php" {
 			continue
@@ -35,7 +43,9 @@ func (m *Manager) SyncAll() error {
 		}
 
 		if _, err := WriteToolVhost(m.Base, t, m.PhpSockAbs); err != nil {
-			return err
+			fmt.Printf("[Tools] Skipping %s: %v\n", t.Name, err)
+			skipped[t.Name] = err.Error()
+			continue
 		}
 	}
 
@@ -46,5 +56,20 @@ func (m *Manager) SyncAll() error {
 		}
 	}
 
+	names := make([]string, 0, len(manifests))
+	for _, t := range manifests {
+		if _, bad := skipped[t.Name]; !bad {
+			names = append(names, t.Name)
+		}
+	}
+	data := map[string]any{"tools": names}
+	if len(skipped) > 0 {
+		data["skipped"] = skipped
+	}
+	events.Publish(events.Event{
+		Type: events.ToolsSynced,
+		Data: data,
+	})
+
 	return nil
 }
@@ -1,9 +1,13 @@
 package tools
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
-	"text/template"
+
+	"pit/internal/certs"
+	"pit/internal/services"
+	"pit/internal/templates"
 )
 
 type NginxToolVhost struct {
@@ -11,27 +15,11 @@ type NginxToolVhost struct {
 	RootAbs    string
 	Index      string
 	PhpSock    string
-}
-
-var toolVhostTpl = template.Must(template.New("vhost").Parse(`
-server {
-    listen 80;
-    server_name {{.ServerName}};
-
-    root {{.RootAbs}};
-    index {{.Index}};
 
-    location / {
-        try_files $uri $uri/ /{{.Index}}?$query_string;
-    }
-
-    location ~ \.php$ {
-        include fastcgi_params;
-        fastcgi_param SCRIPT_FILENAME $document_root$fastcgi_script_name;
-        fastcgi_pass unix:{{.PhpSock}};
-    }
+	// set once the local CA exists
+	TLSCert string
+	TLSKey  string
 }
-`))
 
 func WriteToolVhost(base string, m Manifest, phpSockAbs string) (string, error) {
 	outDir := filepath.Join(base, "nginx", "conf.d", "tools")
@@ -42,19 +30,30 @@ func WriteToolVhost(base string, m Manifest, phpSockAbs string) (string, error)
 	rootAbs := filepath.Join(base, m.Root)
 	outPath := filepath.Join(outDir, m.Name+".conf")
 
-	f, err := os.Create(outPath)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-
 	data := NginxToolVhost{
 		ServerName: m.Domain,
 		RootAbs:    rootAbs,
 		Index:      m.Index,
 		PhpSock:    phpSockAbs,
 	}
-	if err := toolVhostTpl.Execute(f, data); err != nil {
+
+	if ca := certs.New(base); ca.Exists() {
+		pair, err := ca.Issue([]string{m.Domain})
+		if err != nil {
+			fmt.Printf("[Certs] %s: %v (http only)\n", m.Domain, err)
+		} else {
+			data.TLSCert, data.TLSKey = pair.Cert, pair.Key
+		}
+	}
+	// tool.tmpl, overridable in config/templates/
+	conf, err := templates.New(base).Execute("tool.tmpl", data)
+	if err != nil {
+		return "", err
+	}
+
+	// checked on its own with nginx -t; a rejected vhost keeps the
+	// previous <name>.conf (if any) in place
+	if err := services.InstallNginxServer(filepath.Join(base, "nginx"), outPath, []byte(conf)); err != nil {
 		return "", err
 	}
 	return outPath, nil
@@ -0,0 +1,255 @@
+package util
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ----------------------------------------------------------
+// BUILD ARCHIVES (portable php / nginx)
+// ----------------------------------------------------------
+
+// ExtractTar unpacks a .tar, .tar.gz or .tgz into dst. Regular files,
+// directories and symlinks are kept; entries (or link targets) that
+// would land outside dst are refused, and so are entries written
+// through a symlink extracted earlier (d -> ., e -> d/.., e/evil).
+func ExtractTar(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(src, ".gz") || strings.HasSuffix(src, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+
+		target, err := insideDir(dst, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if err := noSymlinkParents(dst, target); err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			// never write through a link of the same name
+			if err := removeSymlink(target); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, fs.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if _, err := insideDir(dst, filepath.Join(filepath.Dir(hdr.Name), hdr.Linkname)); err != nil || filepath.IsAbs(hdr.Linkname) {
+				return fmt.Errorf("%s: symlink %s points outside the archive", src, hdr.Name)
+			}
+			_ = os.MkdirAll(filepath.Dir(target), 0o755)
+			_ = removeSymlink(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// CopyTree copies the directory src to dst (files, modes, symlinks).
+func CopyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			in, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			return writeFile(target, in, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// StripSingleDir moves the content of dir/<only-child>/ up into dir
+// when an archive wraps everything in one top-level directory.
+func StripSingleDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return err
+	}
+
+	inner := filepath.Join(dir, entries[0].Name())
+	children, err := os.ReadDir(inner)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		if err := os.Rename(filepath.Join(inner, c.Name()), filepath.Join(dir, c.Name())); err != nil {
+			return err
+		}
+	}
+	return os.Remove(inner)
+}
+
+// VerifyChecksums checks every "<sha256>  <path>" line of a
+// sha256sum-style manifest against the files in dir and returns the
+// verified paths.
+func VerifyChecksums(dir, manifest string) ([]string, error) {
+	f, err := os.Open(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var verified []string
+	sc := bufio.NewScanner(f)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sum, name, ok := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		if !ok || len(sum) != sha256.Size*2 || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected \"<sha256>  <file>\"", manifest, lineNo)
+		}
+
+		path, err := insideDir(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		got, err := fileSHA256(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if !strings.EqualFold(got, sum) {
+			return nil, fmt.Errorf("%s: checksum mismatch", name)
+		}
+		verified = append(verified, filepath.Clean(name))
+	}
+	return verified, sc.Err()
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func insideDir(dir, name string) (string, error) {
+	target := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s: path outside the build", name)
+	}
+	return target, nil
+}
+
+// noSymlinkParents fails when a directory between dir and target is a
+// symlink, so nothing is written through one.
+func noSymlinkParents(dir, target string) error {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+
+	cur := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		cur = filepath.Join(cur, part)
+		info, err := os.Lstat(cur)
+		if os.IsNotExist(err) {
+			return nil // the rest gets created as plain directories
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			name, _ := filepath.Rel(dir, target)
+			link, _ := filepath.Rel(dir, cur)
+			return fmt.Errorf("%s: path goes through symlink %s", name, link)
+		}
+	}
+	return nil
+}
+
+func removeSymlink(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+func writeFile(path string, r io.Reader, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
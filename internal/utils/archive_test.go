@@ -0,0 +1,149 @@
+package util
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name, link, body string
+	dir              bool
+}
+
+func writeTar(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		switch {
+		case e.dir:
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0o755, 0
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractTar(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "build.tar")
+	dst := filepath.Join(tmp, "out")
+
+	writeTar(t, src, []tarEntry{
+		{name: "php/", dir: true},
+		{name: "php/bin/php", body: "#!/bin/sh\n"},
+		{name: "php/lib/libphp.so.8", body: "elf"},
+		{name: "php/lib/libphp.so", link: "libphp.so.8"},
+	})
+	if err := ExtractTar(src, dst); err != nil {
+		t.Fatalf("ExtractTar: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "php", "bin", "php"))
+	if err != nil || string(data) != "#!/bin/sh\n" {
+		t.Errorf("php/bin/php = %q, %v", data, err)
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "php", "lib", "libphp.so")); err != nil || link != "libphp.so.8" {
+		t.Errorf("php/lib/libphp.so -> %q, %v", link, err)
+	}
+}
+
+func TestExtractTarRefusesEscapes(t *testing.T) {
+	cases := map[string][]tarEntry{
+		"dotdot":          {{name: "../evil", body: "x"}},
+		"absolute link":   {{name: "l", link: "/etc"}},
+		"link outside":    {{name: "l", link: "../.."}},
+		"through symlink": {{name: "d", link: "."}, {name: "e", link: "d/.."}, {name: "e/evil", body: "x"}},
+		"into symlink":    {{name: "sub/", dir: true}, {name: "sub/l", link: ".."}, {name: "sub/l/evil", body: "x"}},
+	}
+
+	for name, entries := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			src := filepath.Join(tmp, "build.tar")
+			dst := filepath.Join(tmp, "a", "b", "out")
+			if err := os.MkdirAll(dst, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			writeTar(t, src, entries)
+
+			if err := ExtractTar(src, dst); err == nil {
+				t.Error("ExtractTar: expected an error")
+			}
+			for _, p := range []string{
+				filepath.Join(tmp, "evil"),
+				filepath.Join(tmp, "a", "evil"),
+				filepath.Join(tmp, "a", "b", "evil"),
+			} {
+				if _, err := os.Lstat(p); err == nil {
+					t.Errorf("%s written outside dst", p)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", "php"), []byte("php"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("php"))
+	good := hex.EncodeToString(sum[:])
+	bad := hex.EncodeToString(make([]byte, sha256.Size))
+
+	cases := []struct {
+		name     string
+		manifest string
+		wantErr  bool
+	}{
+		{"ok", "# build\n" + good + "  bin/php\n", false},
+		{"binary mode", good + " *bin/php\n", false},
+		{"mismatch", bad + "  bin/php\n", true},
+		{"missing file", good + "  bin/php-fpm\n", true},
+		{"outside", good + "  ../php\n", true},
+		{"malformed", "deadbeef  bin/php\n", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			manifest := filepath.Join(t.TempDir(), "SHA256SUMS")
+			if err := os.WriteFile(manifest, []byte(c.manifest), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := VerifyChecksums(dir, manifest)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("VerifyChecksums: expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil || len(got) != 1 || got[0] != filepath.Join("bin", "php") {
+				t.Errorf("VerifyChecksums = %v, %v", got, err)
+			}
+		})
+	}
+}
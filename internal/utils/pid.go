@@ -35,15 +35,18 @@ func IsAlive(pid int) bool {
 	return err == nil
 }
 
-func StopPID(pidFile string) {
+// StopPID gracefully stops the process recorded in pidFile (see
+// StopProcess) and removes the pid file.
+func StopPID(pidFile string, sig syscall.Signal) StopResult {
 	pid := GetPID(pidFile)
 	if pid == 0 || !IsAlive(pid) {
 		_ = os.Remove(pidFile)
-		return
+		return StopResult{PID: pid, Step: StopNotRunning}
 	}
-	proc, _ := os.FindProcess(pid)
-	_ = proc.Kill()
+
+	res, _ := StopProcess(pid, sig, 0, nil)
 	_ = os.Remove(pidFile)
+	return res
 }
 
 func CleanupPID(pidFile string) {
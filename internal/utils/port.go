@@ -2,34 +2,21 @@ package util
 
 import (
 	"fmt"
-	"os/exec"
-	"strconv"
-	"strings"
-)
+	"syscall"
 
-func KillPort(port int) {
-	out, err := exec.Command("lsof", "-t", "-i", fmt.Sprintf(":%d", port)).Output()
-	if err != nil {
-		return
-	}
+	"pit/internal/procfs"
+)
 
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+// KillPort kills the processes listening on port, but only those
+// running a binary under owner (the pit base dir). Foreign listeners
+// are reported and left alone.
+func KillPort(owner string, port int) {
+	for _, pid := range procfs.PortOwners(port) {
+		if !procfs.OwnedBy(pid, owner) {
+			fmt.Printf("[Port] %d is held by pid %d (%s), not owned by pit — skipping\n",
+				port, pid, procfs.Comm(pid))
 			continue
 		}
-		// kill -9 <pid>
-		_ = exec.Command("kill", "-9", line).Run()
-	}
-}
-
-func FindFreePort(start int) int {
-	for p := start; p < start+50; p++ {
-		cmd := exec.Command("lsof", "-i", ":"+strconv.Itoa(p))
-		if err := cmd.Run(); err != nil {
-			return p
-		}
+		_ = syscall.Kill(pid, syscall.SIGKILL)
 	}
-	return -1
 }
@@ -0,0 +1,140 @@
+package util
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	"pit/internal/procfs"
+)
+
+// StopTimeout is how long StopProcess waits after the graceful signal
+// before escalating to SIGKILL. Overridden from config/engine.json.
+var StopTimeout = 10 * time.Second
+
+// StopStep names the step that actually ended a process.
+type StopStep string
+
+const (
+	StopNotRunning StopStep = "not-running"
+	StopGraceful   StopStep = "graceful"
+	StopKilled     StopStep = "killed"
+	StopFailed     StopStep = "failed"
+)
+
+type StopResult struct {
+	PID    int
+	Signal syscall.Signal
+	Step   StopStep
+	Took   time.Duration
+}
+
+func (r StopResult) String() string {
+	switch r.Step {
+	case StopNotRunning:
+		return fmt.Sprintf("pid %d was not running", r.PID)
+	case StopGraceful:
+		return fmt.Sprintf("pid %d exited after SIG%s in %s", r.PID, signalName(r.Signal), r.Took.Round(time.Millisecond))
+	case StopKilled:
+		return fmt.Sprintf("pid %d ignored SIG%s, killed after %s", r.PID, signalName(r.Signal), r.Took.Round(time.Millisecond))
+	default:
+		return fmt.Sprintf("pid %d could not be stopped", r.PID)
+	}
+}
+
+// GracefulSignal returns the signal that makes program finish in-flight
+// work before exiting: QUIT for nginx and php-fpm, TERM otherwise.
+func GracefulSignal(program string) syscall.Signal {
+	switch program {
+	case "nginx", "php-fpm":
+		return syscall.SIGQUIT
+	default:
+		return syscall.SIGTERM
+	}
+}
+
+// StopProcess sends sig to pid and waits up to timeout (StopTimeout when
+// zero) for it to exit, escalating to SIGKILL only on timeout.
+//
+// exited may be nil; when set (pid is our own child) it is used instead
+// of polling /proc, since an unreaped child lingers as a zombie.
+func StopProcess(pid int, sig syscall.Signal, timeout time.Duration, exited <-chan struct{}) (StopResult, error) {
+	res := StopResult{PID: pid, Signal: sig}
+
+	if pid <= 0 || procfs.Exited(pid) {
+		res.Step = StopNotRunning
+		return res, nil
+	}
+	if timeout <= 0 {
+		timeout = StopTimeout
+	}
+
+	start := time.Now()
+	if err := syscall.Kill(pid, sig); err != nil {
+		if err == syscall.ESRCH {
+			res.Step = StopNotRunning
+			return res, nil
+		}
+		res.Step = StopFailed
+		return res, err
+	}
+
+	if waitExit(pid, timeout, exited) {
+		res.Step = StopGraceful
+		res.Took = time.Since(start)
+		return res, nil
+	}
+
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		res.Step = StopFailed
+		return res, err
+	}
+
+	// SIGKILL cannot be ignored; give the kernel a moment to reap
+	waitExit(pid, 2*time.Second, exited)
+	res.Step = StopKilled
+	res.Took = time.Since(start)
+	return res, nil
+}
+
+func waitExit(pid int, timeout time.Duration, exited <-chan struct{}) bool {
+	deadline := time.After(timeout)
+
+	if exited != nil {
+		select {
+		case <-exited:
+			return true
+		case <-deadline:
+			return false
+		}
+	}
+
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+
+	for {
+		if procfs.Exited(pid) {
+			return true
+		}
+		select {
+		case <-tick.C:
+		case <-deadline:
+			return procfs.Exited(pid)
+		}
+	}
+}
+
+func signalName(sig syscall.Signal) string {
+	switch sig {
+	case syscall.SIGQUIT:
+		return "QUIT"
+	case syscall.SIGTERM:
+		return "TERM"
+	case syscall.SIGINT:
+		return "INT"
+	case syscall.SIGKILL:
+		return "KILL"
+	default:
+		return fmt.Sprintf("(%d)", int(sig))
+	}
+}
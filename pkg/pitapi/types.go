@@ -0,0 +1,308 @@
+// Package pitapi holds the request and response bodies of the pit HTTP
+// API (v2). The server encodes these, pkg/pitclient decodes them and
+// /openapi.json is generated from them, so the three cannot drift.
+package pitapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ================================
+// SERVICES / ENGINE
+// ================================
+
+type ServiceStatus struct {
+	Running bool `json:"running"`
+	PID     int  `json:"pid"`
+	Port    int  `json:"port"`
+
+	// seconds since the current process started
+	Uptime int64 `json:"uptime"`
+	// healthy | unhealthy | stopped
+	Health string `json:"health,omitempty"`
+
+	// supervisor bookkeeping (empty for unsupervised services)
+	Policy   string `json:"policy,omitempty"`
+	Restarts int    `json:"restarts"`
+	LastExit string `json:"last_exit,omitempty"`
+
+	// cron jobs (cron:<job> entries of a project)
+	Schedule string     `json:"schedule,omitempty"`
+	LastRun  *time.Time `json:"last_run,omitempty"`
+	NextRun  *time.Time `json:"next_run,omitempty"`
+	ExitCode *int       `json:"exit_code,omitempty"`
+}
+
+// EngineStatus is the full picture served to `pit status`.
+type EngineStatus struct {
+	PID        int                                 `json:"pid"`
+	Uptime     int64                               `json:"uptime"`
+	PHPVersion string                              `json:"php_version"`
+	Services   map[string]ServiceStatus            `json:"services"`
+	Projects   map[string]map[string]ServiceStatus `json:"projects"`
+}
+
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// ================================
+// PHP
+// ================================
+
+type PHPVersionRequest struct {
+	Version string `json:"version"`
+}
+
+type PHPVersionResponse struct {
+	Version string `json:"version"`
+}
+
+type PHPVersionsResponse struct {
+	Versions []string `json:"versions"`
+}
+
+// PHPExtension is one extension of a PHP build. Enabled means loaded
+// for every project of the version (php.ini, conf.d or compiled in);
+// Projects lists the ones that load it in their own pool.
+type PHPExtension struct {
+	Name     string   `json:"name"`
+	Shared   bool     `json:"shared"`  // .so in the extension_dir
+	Zend     bool     `json:"zend"`    // zend_extension, version-wide only
+	Builtin  bool     `json:"builtin"` // compiled into the binary
+	Enabled  bool     `json:"enabled"`
+	Projects []string `json:"projects,omitempty"`
+}
+
+type PHPExtensionsResponse struct {
+	Version    string         `json:"version"`
+	Extensions []PHPExtension `json:"extensions"`
+}
+
+// PHPExtensionRequest picks the scope of enable/disable: the PHP
+// version (default: the current one) or a single project, which always
+// uses its own PHP version.
+type PHPExtensionRequest struct {
+	Version string `json:"version,omitempty"`
+	Project string `json:"project,omitempty"`
+}
+
+// ================================
+// PROJECTS
+// ================================
+
+// ProjectConfig mirrors projects/<name>/.pit/config.json.
+type ProjectConfig struct {
+	Name       string   `json:"name"`
+	PHPVersion string   `json:"php_version"`
+	Port       int      `json:"port"`
+	Root       string   `json:"root"`
+	Framework  string   `json:"framework,omitempty"`
+	Domains    []string `json:"domains,omitempty"`
+	Aliases    []string `json:"aliases,omitempty"`
+
+	Type         string            `json:"type,omitempty"` // php (default) or proxy
+	Command      string            `json:"command,omitempty"`
+	UpstreamPort int               `json:"upstream_port,omitempty"`
+	Env          map[string]string `json:"env,omitempty"`
+	EnvFiles     []string          `json:"env_files,omitempty"`
+
+	Processes []ProcessConfig `json:"processes,omitempty"`
+	Cron      []CronJob       `json:"cron,omitempty"`
+
+	// php.ini overrides and FPM pool directives for this project
+	PHPIni map[string]IniValue `json:"php_ini,omitempty"`
+	Pool   map[string]IniValue `json:"pool,omitempty"`
+
+	// extensions loaded in this project's pool only
+	Extensions []string `json:"extensions,omitempty"`
+
+	Debug *DebugConfig `json:"debug,omitempty"`
+}
+
+// DebugConfig turns Xdebug on for one project. A project with a debug
+// block runs its pool in its own FPM master, so toggling it never
+// reloads other projects.
+type DebugConfig struct {
+	Enabled    bool   `json:"enabled"`
+	Mode       string `json:"mode,omitempty"`        // xdebug.mode: debug (default), profile, coverage, ...
+	ClientPort int    `json:"client_port,omitempty"` // IDE listen port, picked from 9003 up
+}
+
+// IniValue is a php.ini / FPM pool value. JSON numbers and booleans are
+// accepted too (`"memory_limit": "1G"`, `"max_execution_time": 0`,
+// `"display_errors": true`).
+type IniValue string
+
+func (v *IniValue) UnmarshalJSON(b []byte) error {
+	var raw any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	switch x := raw.(type) {
+	case string:
+		*v = IniValue(x)
+	case bool:
+		*v = "off"
+		if x {
+			*v = "on"
+		}
+	case float64:
+		*v = IniValue(strconv.FormatFloat(x, 'f', -1, 64))
+	default:
+		return fmt.Errorf("ini value must be a string, number or boolean, got %s", b)
+	}
+	return nil
+}
+
+// CronJob runs Command on a 5-field cron Schedule while the project is
+// running.
+type CronJob struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"` // "* * * * *", "@daily", ...
+	Command  string `json:"command"`
+	Dir      string `json:"dir,omitempty"` // relative to the project
+}
+
+// ProcessConfig is a sidecar command (queue worker, asset watcher, ...)
+// started and stopped with the project.
+type ProcessConfig struct {
+	Name    string            `json:"name"`
+	Command string            `json:"command"`
+	Dir     string            `json:"dir,omitempty"` // relative to the project
+	Env     map[string]string `json:"env,omitempty"`
+	Restart string            `json:"restart,omitempty"` // always (default), on-failure, never
+}
+
+// ProjectConfigPatch lists the fields an update may change; nil/empty
+// fields are left untouched.
+type ProjectConfigPatch struct {
+	Port       *int   `json:"port,omitempty"`
+	PHPVersion string `json:"php_version,omitempty"`
+
+	// replace the whole list; an empty list resets Domains to
+	// <name>.test and clears Aliases
+	Domains *[]string `json:"domains,omitempty"`
+	Aliases *[]string `json:"aliases,omitempty"`
+
+	// "" or "auto" goes back to detection
+	Framework *string `json:"framework,omitempty"`
+	// document root relative to the project directory
+	Root *string `json:"root,omitempty"`
+
+	// proxy projects
+	Type         *string            `json:"type,omitempty"`
+	Command      *string            `json:"command,omitempty"`
+	UpstreamPort *int               `json:"upstream_port,omitempty"`
+	Env          *map[string]string `json:"env,omitempty"`
+
+	// dotenv files relative to the project, read in order
+	EnvFiles *[]string `json:"env_files,omitempty"`
+
+	// replace the whole list of sidecar processes
+	Processes *[]ProcessConfig `json:"processes,omitempty"`
+	Cron      *[]CronJob       `json:"cron,omitempty"`
+
+	// replace the whole map
+	PHPIni *map[string]IniValue `json:"php_ini,omitempty"`
+	Pool   *map[string]IniValue `json:"pool,omitempty"`
+
+	// replace the whole list of per-project extensions
+	Extensions *[]string `json:"extensions,omitempty"`
+
+	// empty mode / client port keep the current (or default) value
+	Debug *DebugConfig `json:"debug,omitempty"`
+}
+
+type ProjectCreateRequest struct {
+	Name string `json:"name"`
+}
+
+type ProjectListResponse struct {
+	Projects []string `json:"projects"`
+}
+
+type ProjectDeleteResponse struct {
+	Project    string `json:"project"`
+	ArchivedTo string `json:"archived_to,omitempty"`
+}
+
+type ProjectStateResponse struct {
+	Project  string                   `json:"project"`
+	Status   string                   `json:"status"`
+	Services map[string]ServiceStatus `json:"services"`
+}
+
+type PortRequest struct {
+	Port int `json:"port"`
+}
+
+// ================================
+// EVENTS
+// ================================
+
+// Event is one message of the GET /v2/events stream.
+type Event struct {
+	ID      uint64         `json:"id"`
+	Type    string         `json:"type"`
+	Time    time.Time      `json:"time"`
+	Project string         `json:"project,omitempty"`
+	Service string         `json:"service,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// ================================
+// ERRORS
+// ================================
+//
+//	{"error": {"code": "project_not_found", "message": "project not found: foo"}}
+
+// Error codes returned by /v2. Clients switch on Code, never on Message.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeInvalidName        = "invalid_name"
+	CodeInvalidPort        = "invalid_port"
+	CodePortConflict       = "port_conflict"
+	CodePortInUse          = "port_in_use"
+	CodeNoFreePort         = "no_free_port"
+	CodeInvalidDomain      = "invalid_domain"
+	CodeDomainConflict     = "domain_conflict"
+	CodeInvalidFramework   = "invalid_framework"
+	CodeInvalidRoot        = "invalid_root"
+	CodeInvalidConfig      = "invalid_config"
+	CodeInvalidProject     = "invalid_project_config"
+	CodeProjectNotFound    = "project_not_found"
+	CodeProjectExists      = "project_exists"
+	CodePHPVersionNotFound = "php_version_not_found"
+	CodeExtensionNotFound  = "extension_not_found"
+	CodeExtensionLocked    = "extension_locked"
+	CodeEngineRunning      = "engine_running"
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInternal           = "internal"
+)
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// set for invalid_config: what nginx -t reported
+	Issues []ConfigIssue `json:"issues,omitempty"`
+}
+
+// ConfigIssue is one problem nginx found in a generated config.
+type ConfigIssue struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Level   string `json:"level"` // emerg, alert, crit, error, warn
+	Message string `json:"message"`
+}
+
+type ErrorEnvelope struct {
+	Error ErrorBody `json:"error"`
+}
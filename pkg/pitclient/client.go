@@ -0,0 +1,345 @@
+// Package pitclient is a typed client for the pit HTTP API (v2).
+//
+//	c := pitclient.NewUnix("/path/to/pit/runtime/pit.sock")
+//	st, err := c.Status(ctx)
+//
+// Over loopback TCP (api.tcp in config/engine.json) pass the token:
+//
+//	c := pitclient.New("http://127.0.0.1:7070", token)
+package pitclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pit/pkg/pitapi"
+)
+
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// New returns a client for a TCP endpoint such as http://127.0.0.1:7070.
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		HTTP:    &http.Client{},
+	}
+}
+
+// NewUnix returns a client talking to the engine's control socket.
+func NewUnix(socketPath string) *Client {
+	return &Client{
+		BaseURL: "http://pit",
+		HTTP: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// ----------------------------
+// ERRORS
+// ----------------------------
+
+// Error is a non-2xx answer of the API.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+
+	// what nginx -t reported, for CodeInvalidConfig
+	Issues []pitapi.ConfigIssue
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("pit api: %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return e.Message
+}
+
+// ErrorCode returns the pitapi.Code* of err, or "" if err did not come
+// from the API.
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// ----------------------------
+// TRANSPORT
+// ----------------------------
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var rd io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func decodeError(resp *http.Response) error {
+	e := &Error{Status: resp.StatusCode}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var env pitapi.ErrorEnvelope
+	if json.Unmarshal(raw, &env) == nil && env.Error.Code != "" {
+		e.Code = env.Error.Code
+		e.Message = env.Error.Message
+		e.Issues = env.Error.Issues
+	}
+	return e
+}
+
+func projectPath(name string, rest ...string) string {
+	p := "/v2/projects/" + url.PathEscape(name)
+	for _, r := range rest {
+		p += "/" + r
+	}
+	return p
+}
+
+// ----------------------------
+// ENGINE
+// ----------------------------
+
+func (c *Client) Status(ctx context.Context) (*pitapi.EngineStatus, error) {
+	var st pitapi.EngineStatus
+	if err := c.do(ctx, http.MethodGet, "/v2/status", nil, nil, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (c *Client) Services(ctx context.Context) (map[string]pitapi.ServiceStatus, error) {
+	var out map[string]pitapi.ServiceStatus
+	if err := c.do(ctx, http.MethodGet, "/v2/services", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) StartEngine(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/v2/engine/start", nil, nil, nil)
+}
+
+// StopEngine tears the stack down; the engine process exits afterwards.
+func (c *Client) StopEngine(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/v2/engine/stop", nil, nil, nil)
+}
+
+func (c *Client) ReloadEngine(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/v2/engine/reload", nil, nil, nil)
+}
+
+// ----------------------------
+// PHP
+// ----------------------------
+
+func (c *Client) PHPVersions(ctx context.Context) ([]string, error) {
+	var out pitapi.PHPVersionsResponse
+	if err := c.do(ctx, http.MethodGet, "/v2/php/versions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Versions, nil
+}
+
+func (c *Client) PHPVersion(ctx context.Context) (string, error) {
+	var out pitapi.PHPVersionResponse
+	if err := c.do(ctx, http.MethodGet, "/v2/php/current", nil, nil, &out); err != nil {
+		return "", err
+	}
+	return out.Version, nil
+}
+
+func (c *Client) UsePHPVersion(ctx context.Context, version string) error {
+	return c.do(ctx, http.MethodPut, "/v2/php/current", nil, pitapi.PHPVersionRequest{Version: version}, nil)
+}
+
+// PHPExtensions lists the extensions of a PHP build; "" is the current
+// version.
+func (c *Client) PHPExtensions(ctx context.Context, version string) (*pitapi.PHPExtensionsResponse, error) {
+	var q url.Values
+	if version != "" {
+		q = url.Values{"version": {version}}
+	}
+	var out pitapi.PHPExtensionsResponse
+	if err := c.do(ctx, http.MethodGet, "/v2/php/extensions", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EnablePHPExtension turns ext on for req.Version, or for req.Project
+// only when set.
+func (c *Client) EnablePHPExtension(ctx context.Context, ext string, req pitapi.PHPExtensionRequest) (*pitapi.PHPExtension, error) {
+	return c.phpExtensionAction(ctx, ext, "enable", req)
+}
+
+func (c *Client) DisablePHPExtension(ctx context.Context, ext string, req pitapi.PHPExtensionRequest) (*pitapi.PHPExtension, error) {
+	return c.phpExtensionAction(ctx, ext, "disable", req)
+}
+
+func (c *Client) phpExtensionAction(ctx context.Context, ext, action string, req pitapi.PHPExtensionRequest) (*pitapi.PHPExtension, error) {
+	var out pitapi.PHPExtension
+	path := "/v2/php/extensions/" + url.PathEscape(ext) + "/" + action
+	if err := c.do(ctx, http.MethodPost, path, nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ----------------------------
+// PROJECTS
+// ----------------------------
+
+func (c *Client) Projects(ctx context.Context) ([]string, error) {
+	var out pitapi.ProjectListResponse
+	if err := c.do(ctx, http.MethodGet, "/v2/projects", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Projects, nil
+}
+
+func (c *Client) CreateProject(ctx context.Context, name string) (*pitapi.ProjectConfig, error) {
+	var cfg pitapi.ProjectConfig
+	if err := c.do(ctx, http.MethodPost, "/v2/projects", nil, pitapi.ProjectCreateRequest{Name: name}, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Client) Project(ctx context.Context, name string) (*pitapi.ProjectConfig, error) {
+	var cfg pitapi.ProjectConfig
+	if err := c.do(ctx, http.MethodGet, projectPath(name), nil, nil, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// UpdateProject applies patch and restarts the project.
+func (c *Client) UpdateProject(ctx context.Context, name string, patch pitapi.ProjectConfigPatch) (*pitapi.ProjectConfig, error) {
+	var cfg pitapi.ProjectConfig
+	if err := c.do(ctx, http.MethodPatch, projectPath(name), nil, patch, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// DeleteProject stops and removes a project; with archive it is moved
+// to archive/ and the new location is returned.
+func (c *Client) DeleteProject(ctx context.Context, name string, archive bool) (string, error) {
+	var q url.Values
+	if archive {
+		q = url.Values{"archive": {"true"}}
+	}
+	var out pitapi.ProjectDeleteResponse
+	if err := c.do(ctx, http.MethodDelete, projectPath(name), q, nil, &out); err != nil {
+		return "", err
+	}
+	return out.ArchivedTo, nil
+}
+
+func (c *Client) StartProject(ctx context.Context, name string) (*pitapi.ProjectStateResponse, error) {
+	return c.projectAction(ctx, name, "start")
+}
+
+func (c *Client) StopProject(ctx context.Context, name string) (*pitapi.ProjectStateResponse, error) {
+	return c.projectAction(ctx, name, "stop")
+}
+
+func (c *Client) RestartProject(ctx context.Context, name string) (*pitapi.ProjectStateResponse, error) {
+	return c.projectAction(ctx, name, "restart")
+}
+
+func (c *Client) projectAction(ctx context.Context, name, action string) (*pitapi.ProjectStateResponse, error) {
+	var out pitapi.ProjectStateResponse
+	if err := c.do(ctx, http.MethodPost, projectPath(name, action), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) ProjectStatus(ctx context.Context, name string) (map[string]pitapi.ServiceStatus, error) {
+	var out map[string]pitapi.ServiceStatus
+	if err := c.do(ctx, http.MethodGet, projectPath(name, "status"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) SetProjectPort(ctx context.Context, name string, port int) (*pitapi.ProjectConfig, error) {
+	var cfg pitapi.ProjectConfig
+	if err := c.do(ctx, http.MethodPut, projectPath(name, "port"), nil, pitapi.PortRequest{Port: port}, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// ----------------------------
+// TOOLS
+// ----------------------------
+
+func (c *Client) SyncTools(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/v2/tools/sync", nil, nil, nil)
+}
@@ -0,0 +1,81 @@
+package pitclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pit/pkg/pitapi"
+)
+
+// EventFilter narrows the stream: Types are prefixes ("service.",
+// "php.version_switched"), Project an exact project name.
+type EventFilter struct {
+	Types   []string
+	Project string
+}
+
+// EventStream reads GET /v2/events until Close or the context ends.
+type EventStream struct {
+	body io.ReadCloser
+	sc   *bufio.Scanner
+}
+
+func (c *Client) Events(ctx context.Context, f EventFilter) (*EventStream, error) {
+	q := url.Values{}
+	if len(f.Types) > 0 {
+		q.Set("type", strings.Join(f.Types, ","))
+	}
+	if f.Project != "" {
+		q.Set("project", f.Project)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/v2/events", q, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &EventStream{body: resp.Body, sc: sc}, nil
+}
+
+// Next blocks until the next event. It returns io.EOF when the daemon
+// closes the stream.
+func (s *EventStream) Next() (pitapi.Event, error) {
+	for s.sc.Scan() {
+		data, ok := strings.CutPrefix(s.sc.Text(), "data: ")
+		if !ok {
+			continue // id:, event:, retry:, comments
+		}
+
+		var ev pitapi.Event
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return pitapi.Event{}, err
+		}
+		return ev, nil
+	}
+
+	if err := s.sc.Err(); err != nil {
+		return pitapi.Event{}, err
+	}
+	return pitapi.Event{}, io.EOF
+}
+
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}